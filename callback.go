@@ -5,19 +5,19 @@ package dbx
 // ICallbacksManager ...
 type ICallbacksManager interface {
 	// Create could be used to register callbacks for creating object
-	//     db.Callback().Create().After("gorm:create").Register("plugin:run_after_create", func(*Scope) {
+	//     db.Callbacks().Create().Before("plugin:validate", func(scope *dbx.Scope) error {
 	//       // business logic
 	//       ...
 	//
-	//       // set error if some thing wrong happened, will rollback the creating
-	//       scope.Err(errors.New("error"))
+	//       // returning an error aborts the operation
+	//       return errors.New("error")
 	//     })
-	Create()
+	Create() ICallbacksHandler
 	// Update could be used to register callbacks for updating object, refer `Create` for usage
 	Update() ICallbacksHandler
 	// Delete could be used to register callbacks for deleting object, refer `Create` for usage
 	Delete() ICallbacksHandler
-	// Query could be used to register callbacks for querying objects with query methods like `Find`, `First`, `Related`, `Association`...
+	// Query could be used to register callbacks for querying records with query methods like `First`, `All`, `Count`...
 	// Refer `Create` for usage
 	Query() ICallbacksHandler
 }
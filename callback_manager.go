@@ -1,6 +1,46 @@
 package dbx
 
-type CallbackFunc func()
+import "context"
+
+// Operation indicates the type of database operation that triggered a callback.
+type Operation string
+
+const (
+	OpCreate Operation = "create"
+	OpUpdate Operation = "update"
+	OpDelete Operation = "delete"
+	OpQuery  Operation = "query"
+)
+
+// Scope contains the information of the operation that triggered a callback.
+type Scope struct {
+	// Context is the context the operation is bound to, if any
+	Context context.Context
+
+	// Operation is the type of operation being executed
+	Operation Operation
+
+	// Repo is the name of the repository (table if SQL, collection if Mongo) where the operation is executed
+	Repo string
+
+	// Condition is the condition used to match records, as understood by the provider (a BSON document for MongoDB,
+	// the where blocks for SQL)
+	Condition interface{}
+
+	// Documents contains the documents being inserted (OpCreate) or the update document (OpUpdate)
+	Documents []interface{}
+
+	// Result is the object where the result of a query is unmarshalled (OpQuery)
+	Result interface{}
+
+	// Error is the error that made the operation fail. Only set for `OnError` callbacks.
+	Error error
+}
+
+// CallbackFunc is the signature of a callback. Returning an error from a `Before` callback aborts the operation, and
+// returning an error from an `After` callback makes the operation return it. Errors returned by `OnError` callbacks
+// are ignored.
+type CallbackFunc func(scope *Scope) error
 
 type ICallbacksHandler interface {
 	// Before adds a new callback which will be executed before the operation.
@@ -9,7 +49,7 @@ type ICallbacksHandler interface {
 	// After adds a new callback which will be executed after the operation.
 	After(name string, handler CallbackFunc) ICallbacksHandler
 
-	// OnError
+	// OnError adds a new callback which will be executed if the operation fails.
 	OnError(name string, handler CallbackFunc) ICallbacksHandler
 
 	// Remove a registered callback
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jucardi/go-db"
+	"gopkg.in/yaml.v2"
+)
+
+const envPrefix = "DBX_"
+
+// config is the configuration of the tool. It is loaded from a YAML file, environment variables and flags, in that
+// order of precedence from lowest to highest.
+type config struct {
+	// Provider is the name of the registered provider to use (MySQL, PostgreSQL, SQLite, MongoDB, Memory). Uses the
+	// primary provider if empty.
+	Provider string `yaml:"provider"`
+
+	dbx.DbConfig `yaml:",inline"`
+
+	Migrations migrationsConfig `yaml:"migrations"`
+}
+
+type migrationsConfig struct {
+	// Dir is the location of the migration scripts
+	Dir string `yaml:"dir"`
+
+	// RepoIdSuffix is the suffix of the migration repository, see `common.Migrator`
+	RepoIdSuffix string `yaml:"repo_id_suffix"`
+
+	// FailOnOrderMismatch indicates whether the migration fails if scripts are added between migrated scripts
+	FailOnOrderMismatch bool `yaml:"fail_on_order_mismatch"`
+
+	// Recursive indicates whether the scripts in subdirectories are migrated
+	Recursive bool `yaml:"recursive"`
+
+	// Lock indicates whether a lock is acquired before migrating
+	Lock bool `yaml:"lock"`
+
+	// LockWait is the maximum time to wait for the lock
+	LockWait time.Duration `yaml:"lock_wait"`
+}
+
+func defaultConfig() *config {
+	return &config{
+		Migrations: migrationsConfig{
+			Dir:                 "migrations",
+			FailOnOrderMismatch: true,
+		},
+	}
+}
+
+// options registers the configuration flags in a flag set, and applies the configuration sources after the flags are
+// parsed.
+type options struct {
+	configFile string
+	flags      *config
+}
+
+func addConfigFlags(fs *flag.FlagSet) *options {
+	o := &options{flags: &config{}}
+	fs.StringVar(&o.configFile, "config", "", "YAML configuration file (env "+envPrefix+"CONFIG)")
+	fs.StringVar(&o.flags.Provider, "provider", "", "database provider: MySQL, PostgreSQL, SQLite, MongoDB, Memory (env "+envPrefix+"PROVIDER)")
+	fs.StringVar(&o.flags.Host, "host", "", "database host (env "+envPrefix+"HOST)")
+	fs.IntVar(&o.flags.Port, "port", 0, "database port (env "+envPrefix+"PORT)")
+	fs.StringVar(&o.flags.Username, "username", "", "database username (env "+envPrefix+"USERNAME)")
+	fs.StringVar(&o.flags.Password, "password", "", "database password (env "+envPrefix+"PASSWORD)")
+	fs.StringVar(&o.flags.Database, "database", "", "database name (env "+envPrefix+"DATABASE)")
+	fs.StringVar(&o.flags.Options, "options", "", "additional connection options (env "+envPrefix+"OPTIONS)")
+	fs.StringVar(&o.flags.Migrations.Dir, "dir", "", "location of the migration scripts (env "+envPrefix+"MIGRATIONS_DIR)")
+	fs.StringVar(&o.flags.Migrations.RepoIdSuffix, "repo-suffix", "", "suffix of the migration repository (env "+envPrefix+"REPO_ID_SUFFIX)")
+	fs.BoolVar(&o.flags.Migrations.FailOnOrderMismatch, "fail-on-order-mismatch", true, "fail if scripts were added between migrated scripts (env "+envPrefix+"FAIL_ON_ORDER_MISMATCH)")
+	fs.BoolVar(&o.flags.Migrations.Recursive, "recursive", false, "migrate the scripts in subdirectories (env "+envPrefix+"RECURSIVE)")
+	fs.BoolVar(&o.flags.Migrations.Lock, "lock", false, "acquire a lock in the database before migrating (env "+envPrefix+"LOCK)")
+	fs.DurationVar(&o.flags.Migrations.LockWait, "lock-wait", 0, "maximum time to wait for the lock (env "+envPrefix+"LOCK_WAIT)")
+	return o
+}
+
+// load loads the configuration from the YAML file, the environment and the flags explicitly set in the flag set.
+func (o *options) load(fs *flag.FlagSet) (*config, error) {
+	cfg := defaultConfig()
+
+	file := o.configFile
+	if file == "" {
+		file = os.Getenv(envPrefix + "CONFIG")
+	}
+	if file != "" {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the configuration file, %s", err.Error())
+		}
+		if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+			return nil, fmt.Errorf("unable to parse the configuration file '%s', %s", file, err.Error())
+		}
+	}
+
+	if err := loadEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "provider":
+			cfg.Provider = o.flags.Provider
+		case "host":
+			cfg.Host = o.flags.Host
+		case "port":
+			cfg.Port = o.flags.Port
+		case "username":
+			cfg.Username = o.flags.Username
+		case "password":
+			cfg.Password = o.flags.Password
+		case "database":
+			cfg.Database = o.flags.Database
+		case "options":
+			cfg.Options = o.flags.Options
+		case "dir":
+			cfg.Migrations.Dir = o.flags.Migrations.Dir
+		case "repo-suffix":
+			cfg.Migrations.RepoIdSuffix = o.flags.Migrations.RepoIdSuffix
+		case "fail-on-order-mismatch":
+			cfg.Migrations.FailOnOrderMismatch = o.flags.Migrations.FailOnOrderMismatch
+		case "recursive":
+			cfg.Migrations.Recursive = o.flags.Migrations.Recursive
+		case "lock":
+			cfg.Migrations.Lock = o.flags.Migrations.Lock
+		case "lock-wait":
+			cfg.Migrations.LockWait = o.flags.Migrations.LockWait
+		}
+	})
+	return cfg, nil
+}
+
+// loadEnv applies the environment variables set to the configuration
+func loadEnv(cfg *config) error {
+	strs := map[string]*string{
+		"PROVIDER":       &cfg.Provider,
+		"HOST":           &cfg.Host,
+		"USERNAME":       &cfg.Username,
+		"PASSWORD":       &cfg.Password,
+		"DATABASE":       &cfg.Database,
+		"OPTIONS":        &cfg.Options,
+		"MIGRATIONS_DIR": &cfg.Migrations.Dir,
+		"REPO_ID_SUFFIX": &cfg.Migrations.RepoIdSuffix,
+	}
+	for name, field := range strs {
+		if v, ok := os.LookupEnv(envPrefix + name); ok {
+			*field = v
+		}
+	}
+
+	if v, ok := os.LookupEnv(envPrefix + "PORT"); ok {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid value for %sPORT, %s", envPrefix, err.Error())
+		}
+		cfg.Port = port
+	}
+
+	bools := map[string]*bool{
+		"FAIL_ON_ORDER_MISMATCH": &cfg.Migrations.FailOnOrderMismatch,
+		"RECURSIVE":              &cfg.Migrations.Recursive,
+		"LOCK":                   &cfg.Migrations.Lock,
+	}
+	for name, field := range bools {
+		if v, ok := os.LookupEnv(envPrefix + name); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s%s, %s", envPrefix, name, err.Error())
+			}
+			*field = b
+		}
+	}
+
+	if v, ok := os.LookupEnv(envPrefix + "LOCK_WAIT"); ok {
+		wait, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid value for %sLOCK_WAIT, %s", envPrefix, err.Error())
+		}
+		cfg.Migrations.LockWait = wait
+	}
+	return nil
+}
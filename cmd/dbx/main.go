@@ -0,0 +1,52 @@
+// Command dbx runs database migrations and administration tasks using any of the registered go-db providers.
+//
+// Usage:
+//
+//    dbx migrate <up|status|plan|repair|baseline> [flags]
+//
+// The database configuration is loaded from a YAML file (-config), environment variables prefixed by 'DBX_' and
+// flags, in that order of precedence from lowest to highest. Run 'dbx migrate <command> -h' for the available flags.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	_ "github.com/jucardi/go-db/memory"
+	_ "github.com/jucardi/go-db/mgo"
+	_ "github.com/jucardi/go-db/sql"
+)
+
+const usage = `Usage: dbx <command> [arguments]
+
+Commands:
+  migrate up        Migrates the pending scripts, or up to a version with -to
+  migrate status    Shows the status of every known script
+  migrate plan      Shows what 'migrate up' would do without running anything
+  migrate repair    Updates the recorded hash of scripts intentionally modified after they were migrated, asks
+                    for confirmation unless -yes is set
+  migrate baseline  Records the scripts as migrated without running them, up to a script id or version with
+                    -version
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Fprint(stderr, usage)
+		return nil
+	}
+
+	switch args[0] {
+	case "migrate":
+		return runMigrate(args[1:], stdin, stdout, stderr)
+	}
+	fmt.Fprint(stderr, usage)
+	return fmt.Errorf("unknown command '%s'", args[0])
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/jucardi/go-testx/testx"
+)
+
+func TestConfig(t *testing.T) {
+	Convey("Flags override the environment, which overrides the file", t, func() {
+		file := filepath.Join(t.TempDir(), "dbx.yaml")
+		ShouldBeNil(ioutil.WriteFile(file, []byte("provider: PostgreSQL\nhost: file-host\nport: 5432\ndatabase: app\nmigrations:\n  dir: ./scripts\n  lock: true\n"), 0644))
+		t.Setenv("DBX_HOST", "env-host")
+		t.Setenv("DBX_PORT", "6543")
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		opts := addConfigFlags(fs)
+		ShouldBeNil(fs.Parse([]string{"-config", file, "-port", "7654", "-fail-on-order-mismatch=false"}))
+
+		cfg, err := opts.load(fs)
+		ShouldBeNil(err)
+		ShouldEqual("PostgreSQL", cfg.Provider)
+		ShouldEqual("env-host", cfg.Host)
+		ShouldEqual(7654, cfg.Port)
+		ShouldEqual("app", cfg.Database)
+		ShouldEqual("./scripts", cfg.Migrations.Dir)
+		ShouldBeTrue(cfg.Migrations.Lock)
+		ShouldBeTrue(!cfg.Migrations.FailOnOrderMismatch)
+	})
+	Convey("The migration options are read from the environment", t, func() {
+		t.Setenv("DBX_FAIL_ON_ORDER_MISMATCH", "false")
+		t.Setenv("DBX_RECURSIVE", "true")
+		t.Setenv("DBX_LOCK", "1")
+		t.Setenv("DBX_LOCK_WAIT", "90s")
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		opts := addConfigFlags(fs)
+		ShouldBeNil(fs.Parse([]string{"-recursive=false"}))
+
+		cfg, err := opts.load(fs)
+		ShouldBeNil(err)
+		ShouldBeFalse(cfg.Migrations.FailOnOrderMismatch)
+		ShouldBeFalse(cfg.Migrations.Recursive)
+		ShouldBeTrue(cfg.Migrations.Lock)
+		ShouldEqual(90*time.Second, cfg.Migrations.LockWait)
+	})
+	Convey("Invalid environment values are reported", t, func() {
+		t.Setenv("DBX_LOCK", "maybe")
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		opts := addConfigFlags(fs)
+		_, err := opts.load(fs)
+		ShouldError(err)
+		ShouldEqual(`invalid value for DBX_LOCK, strconv.ParseBool: parsing "maybe": invalid syntax`, err.Error())
+
+		t.Setenv("DBX_LOCK", "true")
+		t.Setenv("DBX_LOCK_WAIT", "soon")
+		_, err = opts.load(fs)
+		ShouldError(err)
+		ShouldEqual(`invalid value for DBX_LOCK_WAIT, time: invalid duration "soon"`, err.Error())
+	})
+}
+
+func TestMigrateCommands(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "001_init.js"), []byte("init"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "nested"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	args := func(command string, extra ...string) []string {
+		return append([]string{"migrate", command, "-provider", "Memory", "-database", "cli-test", "-dir", dir}, extra...)
+	}
+
+	Convey("Baseline, status and plan", t, func() {
+		var out bytes.Buffer
+		ShouldBeNil(run(args("plan"), nil, &out, &out))
+		ShouldBeTrue(strings.Contains(out.String(), "Pending:\n  001_init.js"))
+
+		out.Reset()
+		ShouldBeNil(run(args("baseline"), nil, &out, &out))
+		ShouldEqual("Recorded as migrated:\n  001_init.js\n", out.String())
+
+		out.Reset()
+		ShouldBeNil(run(args("status"), nil, &out, &out))
+		ShouldBeTrue(strings.Contains(out.String(), "applied"))
+	})
+	Convey("Repair after a script is modified", t, func() {
+		ShouldBeNil(ioutil.WriteFile(filepath.Join(dir, "001_init.js"), []byte("init // fixed"), 0644))
+
+		var out bytes.Buffer
+		ShouldError(run(args("plan"), nil, &out, &out))
+		ShouldError(run(args("up"), nil, &out, &out))
+
+		out.Reset()
+		ShouldError(run(args("repair"), strings.NewReader("no\n"), &out, &out))
+		ShouldError(run(args("up"), nil, &out, &out))
+
+		out.Reset()
+		ShouldBeNil(run(args("repair"), strings.NewReader("yes\n"), &out, &out))
+		ShouldEqual("The recorded hash of the following scripts will be updated:\n  001_init.js\nType 'yes' to confirm: Repaired:\n  001_init.js\n", out.String())
+
+		out.Reset()
+		ShouldBeNil(run(args("repair", "-yes"), nil, &out, &out))
+		ShouldEqual("Nothing to repair\n", out.String())
+		ShouldBeNil(run(args("up"), nil, &out, &out))
+	})
+	Convey("Unknown commands fail", t, func() {
+		var out bytes.Buffer
+		ShouldError(run([]string{"migrate", "sideways"}, nil, &out, &out))
+	})
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/common"
+)
+
+func runMigrate(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return errors.New("missing migrate command")
+	}
+
+	command := args[0]
+	fs := flag.NewFlagSet("dbx migrate "+command, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	opts := addConfigFlags(fs)
+
+	var to, version string
+	var asJSON, yes bool
+
+	switch command {
+	case "up":
+		fs.StringVar(&to, "to", "", "migrate up to the provided version, reverting the scripts applied after it")
+	case "status", "plan":
+		fs.BoolVar(&asJSON, "json", false, "print the result as JSON")
+	case "baseline":
+		fs.StringVar(&version, "version", "", "id or version of the last script to record, all the scripts if empty")
+	case "repair":
+		fs.BoolVar(&yes, "yes", false, "repair without asking for confirmation")
+		fs.Usage = func() {
+			fmt.Fprintln(stderr, "Usage: dbx migrate repair [flags] [script ids...]")
+			fs.PrintDefaults()
+		}
+	default:
+		fmt.Fprint(stderr, usage)
+		return fmt.Errorf("unknown migrate command '%s'", command)
+	}
+
+	if err := fs.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+
+	cfg, err := opts.load(fs)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	migrator, err := newMigrator(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer migrator.Db.Close()
+
+	switch command {
+	case "up":
+		if to != "" {
+			err = migrator.MigrateTo(to)
+		} else {
+			err = migrator.Migrate()
+		}
+		if err == nil {
+			fmt.Fprintln(stdout, "Migration completed")
+		}
+		return err
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			return writeJSON(stdout, statuses)
+		}
+		return common.FormatStatus(stdout, statuses)
+
+	case "plan":
+		plan, err := migrator.Plan()
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			err = writeJSON(stdout, plan)
+		} else {
+			err = printPlan(stdout, plan)
+		}
+		if err == nil && !plan.CanMigrate() {
+			err = errors.New("the migration would fail")
+		}
+		return err
+
+	case "repair":
+		if !yes {
+			modified, err := migrator.Repair(false, fs.Args()...)
+			if err != nil {
+				return err
+			}
+			if len(modified) == 0 {
+				fmt.Fprintln(stdout, "Nothing to repair")
+				return nil
+			}
+			printList(stdout, "The recorded hash of the following scripts will be updated", modified, "")
+			if !confirm(stdin, stdout) {
+				return errors.New("repair aborted")
+			}
+		}
+		repaired, err := migrator.Repair(true, fs.Args()...)
+		printList(stdout, "Repaired", repaired, "Nothing to repair")
+		return err
+
+	case "baseline":
+		recorded, err := migrator.Baseline(version)
+		printList(stdout, "Recorded as migrated", recorded, "Nothing to record")
+		return err
+	}
+	return nil
+}
+
+func newMigrator(ctx context.Context, cfg *config) (*common.Migrator, error) {
+	var providers []string
+	if cfg.Provider != "" {
+		providers = append(providers, cfg.Provider)
+	}
+
+	db, err := dbx.Dial(&cfg.DbConfig, providers...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to the database, %s", err.Error())
+	}
+
+	return &common.Migrator{
+		Db:                  db,
+		DataDir:             cfg.Migrations.Dir,
+		FailOnOrderMismatch: cfg.Migrations.FailOnOrderMismatch,
+		RepoIdSuffix:        cfg.Migrations.RepoIdSuffix,
+		Recursive:           cfg.Migrations.Recursive,
+		UseLock:             cfg.Migrations.Lock,
+		LockWait:            cfg.Migrations.LockWait,
+		Context:             ctx,
+	}, nil
+}
+
+func printPlan(w io.Writer, plan *common.MigrationPlan) error {
+	sections := []struct {
+		title   string
+		scripts []*common.PlannedScript
+	}{
+		{"Pending", plan.Pending},
+		{"Hash mismatches", plan.HashMismatches},
+		{"Order violations", plan.OrderViolations},
+	}
+
+	if _, err := fmt.Fprintf(w, "Applied: %d\n", len(plan.Applied)); err != nil {
+		return err
+	}
+	for _, section := range sections {
+		if len(section.scripts) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s:\n", section.title); err != nil {
+			return err
+		}
+		for _, s := range section.scripts {
+			line := "  " + s.ScriptId
+			if s.PreviousHash != "" && s.PreviousHash != s.Hash {
+				line += fmt.Sprintf(" (recorded %s, current %s)", s.PreviousHash, s.Hash)
+			}
+			if s.LastError != "" {
+				line += fmt.Sprintf(" (last attempt failed: %s)", s.LastError)
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// confirm asks the user for confirmation, returns true if the answer is 'yes'
+func confirm(stdin io.Reader, stdout io.Writer) bool {
+	fmt.Fprint(stdout, "Type 'yes' to confirm: ")
+	answer, _ := bufio.NewReader(stdin).ReadString('\n')
+	return strings.TrimSpace(strings.ToLower(answer)) == "yes"
+}
+
+func printList(w io.Writer, title string, ids []string, empty string) {
+	if len(ids) == 0 {
+		fmt.Fprintln(w, empty)
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, id := range ids {
+		fmt.Fprintln(w, "  "+id)
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
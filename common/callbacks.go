@@ -0,0 +1,159 @@
+package common
+
+import (
+	"sync"
+
+	"github.com/jucardi/go-db"
+)
+
+var _ dbx.ICallbacksManager = (*CallbacksManager)(nil)
+
+// CallbacksManager is the provider independent implementation of dbx.ICallbacksManager. A nil *CallbacksManager runs
+// the operations without invoking any callbacks.
+type CallbacksManager struct {
+	create *callbacksHandler
+	update *callbacksHandler
+	delete *callbacksHandler
+	query  *callbacksHandler
+}
+
+// NewCallbacksManager creates a new instance of *CallbacksManager with no registered callbacks.
+func NewCallbacksManager() *CallbacksManager {
+	return &CallbacksManager{
+		create: &callbacksHandler{},
+		update: &callbacksHandler{},
+		delete: &callbacksHandler{},
+		query:  &callbacksHandler{},
+	}
+}
+
+func (m *CallbacksManager) Create() dbx.ICallbacksHandler {
+	return m.create
+}
+
+func (m *CallbacksManager) Update() dbx.ICallbacksHandler {
+	return m.update
+}
+
+func (m *CallbacksManager) Delete() dbx.ICallbacksHandler {
+	return m.delete
+}
+
+func (m *CallbacksManager) Query() dbx.ICallbacksHandler {
+	return m.query
+}
+
+// Run executes the provided action invoking the callbacks registered for the operation indicated in the scope. The
+// `Before` callbacks are invoked in order of registration and the first one to fail aborts the operation. The `OnError`
+// callbacks are invoked with the failure error set in the scope if the operation, or any of its callbacks, fails.
+//
+// If the scope is bound to a context which is already done, the operation is not executed and the context error is
+// returned.
+func (m *CallbacksManager) Run(scope *dbx.Scope, action func() error) error {
+	if scope.Context != nil {
+		if err := scope.Context.Err(); err != nil {
+			return err
+		}
+	}
+	if m == nil {
+		return action()
+	}
+
+	h := m.handler(scope.Operation)
+	err := h.invoke(scope, h.before)
+	if err == nil {
+		err = action()
+	}
+	if err == nil {
+		err = h.invoke(scope, h.after)
+	}
+	if err != nil {
+		scope.Error = err
+		_ = h.invoke(scope, h.onError, true)
+	}
+	return err
+}
+
+func (m *CallbacksManager) handler(op dbx.Operation) *callbacksHandler {
+	switch op {
+	case dbx.OpCreate:
+		return m.create
+	case dbx.OpUpdate:
+		return m.update
+	case dbx.OpDelete:
+		return m.delete
+	default:
+		return m.query
+	}
+}
+
+type callback struct {
+	name    string
+	handler dbx.CallbackFunc
+}
+
+type callbacksHandler struct {
+	mx      sync.RWMutex
+	before  []*callback
+	after   []*callback
+	onError []*callback
+}
+
+func (h *callbacksHandler) Before(name string, handler dbx.CallbackFunc) dbx.ICallbacksHandler {
+	return h.register(&h.before, name, handler)
+}
+
+func (h *callbacksHandler) After(name string, handler dbx.CallbackFunc) dbx.ICallbacksHandler {
+	return h.register(&h.after, name, handler)
+}
+
+func (h *callbacksHandler) OnError(name string, handler dbx.CallbackFunc) dbx.ICallbacksHandler {
+	return h.register(&h.onError, name, handler)
+}
+
+func (h *callbacksHandler) Remove(name string) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	h.before = removeCallback(h.before, name)
+	h.after = removeCallback(h.after, name)
+	h.onError = removeCallback(h.onError, name)
+}
+
+// register adds the callback to the given list, replacing any existing callback registered by the same name.
+func (h *callbacksHandler) register(list *[]*callback, name string, handler dbx.CallbackFunc) dbx.ICallbacksHandler {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	for _, c := range *list {
+		if c.name == name {
+			c.handler = handler
+			return h
+		}
+	}
+	*list = append(*list, &callback{name: name, handler: handler})
+	return h
+}
+
+// invoke runs the callbacks in the given list. Stops at the first failure unless 'all' is true.
+func (h *callbacksHandler) invoke(scope *dbx.Scope, list []*callback, all ...bool) error {
+	h.mx.RLock()
+	callbacks := make([]*callback, len(list))
+	copy(callbacks, list)
+	h.mx.RUnlock()
+
+	for _, c := range callbacks {
+		if err := c.handler(scope); err != nil && (len(all) == 0 || !all[0]) {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeCallback(list []*callback, name string) []*callback {
+	var ret []*callback
+	for _, c := range list {
+		if c.name != name {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
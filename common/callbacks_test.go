@@ -0,0 +1,78 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jucardi/go-db"
+	. "github.com/jucardi/go-testx/testx"
+)
+
+func TestCallbacksOrder(t *testing.T) {
+	m := NewCallbacksManager()
+	var calls []string
+
+	m.Create().
+		Before("first", func(scope *dbx.Scope) error {
+			calls = append(calls, "before:"+scope.Repo)
+			return nil
+		}).
+		After("second", func(scope *dbx.Scope) error {
+			calls = append(calls, "after")
+			return nil
+		}).
+		OnError("third", func(scope *dbx.Scope) error {
+			calls = append(calls, "error")
+			return nil
+		})
+
+	Convey("Callbacks invoked around the operation", t, func() {
+		err := m.Run(&dbx.Scope{Operation: dbx.OpCreate, Repo: "users"}, func() error {
+			calls = append(calls, "action")
+			return nil
+		})
+		ShouldBeNil(err)
+		ShouldEqual([]string{"before:users", "action", "after"}, calls)
+	})
+
+	Convey("Callbacks for other operations are not invoked", t, func() {
+		calls = nil
+		ShouldBeNil(m.Run(&dbx.Scope{Operation: dbx.OpQuery}, func() error { return nil }))
+		ShouldLen(calls, 0)
+	})
+}
+
+func TestCallbacksAbort(t *testing.T) {
+	m := NewCallbacksManager()
+	var scopeErr error
+	executed := false
+
+	m.Delete().
+		Before("validate", func(scope *dbx.Scope) error {
+			return errors.New("not allowed")
+		}).
+		OnError("log", func(scope *dbx.Scope) error {
+			scopeErr = scope.Error
+			return nil
+		})
+
+	Convey("Operation aborted by a failing Before callback", t, func() {
+		err := m.Run(&dbx.Scope{Operation: dbx.OpDelete}, func() error {
+			executed = true
+			return nil
+		})
+		ShouldError(err)
+		ShouldEqual("not allowed", err.Error())
+		ShouldEqual(err, scopeErr)
+		ShouldBeTrue(!executed)
+	})
+
+	Convey("Removed callbacks are no longer invoked", t, func() {
+		m.Delete().Remove("validate")
+		ShouldBeNil(m.Run(&dbx.Scope{Operation: dbx.OpDelete}, func() error {
+			executed = true
+			return nil
+		}))
+		ShouldBeTrue(executed)
+	})
+}
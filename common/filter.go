@@ -0,0 +1,33 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+)
+
+// LikeToRegex converts a SQL LIKE pattern into an anchored regular expression. '%' matches any sequence of characters,
+// '_' matches a single character and '\' escapes the next character.
+func LikeToRegex(pattern string) string {
+	builder := strings.Builder{}
+	builder.WriteString("^")
+	escaped := false
+
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			builder.WriteString(".*")
+		case r == '_':
+			builder.WriteString(".")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	builder.WriteString("$")
+	return builder.String()
+}
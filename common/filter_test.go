@@ -0,0 +1,30 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/jucardi/go-db"
+	. "github.com/jucardi/go-testx/testx"
+)
+
+func TestLikeToRegex(t *testing.T) {
+	Convey("LIKE wildcards are translated and the rest is escaped", t, func() {
+		ShouldEqual("^abc.*$", LikeToRegex("abc%"))
+		ShouldEqual("^a.c$", LikeToRegex("a_c"))
+		ShouldEqual("^a%b\\.c$", LikeToRegex("a\\%b.c"))
+	})
+}
+
+func TestHasConditions(t *testing.T) {
+	Convey("Empty conditions do not filter the records", t, func() {
+		q := &AbstractQuery{}
+		q.Where(nil)
+		q.Where(map[string]interface{}{})
+		q.Where(dbx.And())
+		ShouldBeFalse(q.HasConditions())
+
+		q.Or()
+		q.Where(dbx.And(dbx.Eq("name", "bob")))
+		ShouldBeTrue(q.HasConditions())
+	})
+}
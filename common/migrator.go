@@ -1,33 +1,45 @@
 package common
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"os"
-	"strings"
+	"io/fs"
 	"time"
 
 	"github.com/jucardi/go-db"
 	"github.com/jucardi/go-db/logger"
-	"github.com/jucardi/go-osx/paths"
-	"github.com/jucardi/go-streams/streams"
 	"gopkg.in/mgo.v2/bson"
 )
 
 const (
 	MigrationRepo = "_migration"
+
+	// NoTransactionMarker is the marker that disables running a script in a transaction, for statements that can not
+	// run in transactions, e.g. 'CREATE INDEX CONCURRENTLY' in PostgreSQL. It may be placed in a comment anywhere in
+	// the script, e.g. '-- dbx:no-transaction'.
+	NoTransactionMarker = "dbx:no-transaction"
 )
 
 type Migrator struct {
 	// Db is the database client already initialized
 	Db dbx.IDatabase
 
-	// DataDir is the location where the migration scripts are contained
+	// DataDir is the location where the migration scripts are contained. If `FS` is provided, it is the path of the
+	// scripts dir in it ('.' if empty).
 	DataDir string
 
+	// FS is an optional file system where the scripts are located, e.g. an `embed.FS` to ship the scripts compiled in
+	// the binary. If not provided, `DataDir` is a path in the operating system.
+	FS fs.FS
+
+	// Recursive indicates whether the scripts in subdirectories of the data dir are migrated. Scripts are migrated in
+	// order by their path relative to the data dir, which is also the id used to track them. Subdirectories are
+	// skipped if not set.
+	Recursive bool
+
 	// FailOnOrderMismatch indicates whether the migration should fail if the order of previously migrated scripts
 	FailOnOrderMismatch bool
 
@@ -42,6 +54,32 @@ type Migrator struct {
 	// client with AWS DocumentDB where `eval` is not supported, a custom executor using the `mongo` shell CLI could be
 	// implemented instead.
 	ScriptExecutor dbx.ScriptExecutor
+
+	// Context is an optional context for the migration. If the context is done, the migration is aborted before running
+	// the next script.
+	Context context.Context
+
+	// UseLock indicates whether a lock must be acquired in the database before migrating, so only one instance runs the
+	// migration when several instances share the database. The other instances wait for the lock and then verify the
+	// scripts migrated by the instance that held it.
+	UseLock bool
+
+	// LockWait is the maximum time to wait for the lock held by another instance. Defaults to 5 minutes.
+	LockWait time.Duration
+
+	// DisableTransactions disables running each script in a transaction along with its migration record. Scripts are
+	// only run in transactions if the database supports atomic transactions (SQL), and the script does not contain the
+	// `NoTransactionMarker`. Scripts are not run in transactions if a `ScriptExecutor` is provided, since it can not
+	// participate in them.
+	DisableTransactions bool
+
+	// Migrations are migrations implemented in Go, migrated in order by id along with the scripts in `DataDir` and
+	// tracked in the same migration repository. See `Register`.
+	Migrations []*GoMigration
+
+	// LockLease is the time after which a lock that is not renewed is considered abandoned, e.g. if the instance
+	// holding it crashed. The lock is renewed while the migration runs. Defaults to 1 minute.
+	LockLease time.Duration
 }
 
 // Migrate begins a DB migration process by migrating the scripts located in the provided data dir and storing the
@@ -69,141 +107,267 @@ func Migrate(dataDir string, db dbx.IDatabase, failOnOrderMismatch bool, repoIdS
 
 // Migrate begins a DB migration process by migrating the scripts with the configuration contained my the *Migrator
 // instance.
+//
+// Scripts are migrated in order by file name. Scripts may be paired with a down script used to revert them by
+// `Rollback` or `MigrateTo`, using the '.up' and '.down' suffixes before the extension, e.g. '001_users.up.sql' and
+// '001_users.down.sql'. Down scripts are never run by `Migrate`.
 func (m *Migrator) Migrate() error {
-	var infos []*MigrationInfo
-	migrationRepo := MigrationRepo
-	if m.RepoIdSuffix != "" {
-		migrationRepo += "_" + m.RepoIdSuffix
+	return m.migrate("")
+}
+
+// MigrateTo migrates the database to the provided version. The version is the leading number of the script file
+// names (e.g. '001' for '001_users.up.sql'), or the script file name. The scripts up to the version are migrated, and
+// the scripts applied after the version are reverted in reverse order using their down scripts.
+func (m *Migrator) MigrateTo(version string) error {
+	if version == "" {
+		return &dbx.DbError{
+			Message: "A version is required to migrate to.",
+			Code:    dbx.ErrMigrationFailed,
+		}
 	}
+	return m.migrate(version)
+}
 
-	if !m.Db.HasRepo(migrationRepo) {
-		if err := m.Db.CreateRepo(migrationRepo, &MigrationInfo{}); err != nil {
+// migrationStep is a script pending to be migrated
+type migrationStep struct {
+	script *migrationScript
+	hash   string
+	// existing is the record of the script if it was previously reverted
+	existing *MigrationInfo
+}
+
+func (m *Migrator) migrate(version string) error {
+	migrationRepo := m.repoName()
+
+	if err := m.ensureRepo(migrationRepo); err != nil {
+		return err
+	}
+
+	lock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+
+	infos, err := m.loadInfos(migrationRepo)
+	if err != nil {
+		return err
+	}
+
+	scripts, err := m.loadScripts()
+	if err != nil {
+		return err
+	}
+
+	last := len(scripts) - 1
+	if version != "" {
+		if last = findScript(scripts, version); last < 0 {
 			return &dbx.DbError{
-				Message: fmt.Sprintf("Unable to create the required migration repository. %s", err.Error()),
-				Code:    dbx.ErrDbAccess | dbx.ErrDbOperation,
+				Message: fmt.Sprintf("Version '%s' was not found in the migration scripts.", version),
+				Code:    dbx.ErrMigrationFailed,
 			}
 		}
 	}
 
-	if err := m.Db.R(migrationRepo).Where(bson.M{}).Sort("filename").All(&infos); err != nil {
-		return &dbx.DbError{
-			Message: fmt.Sprintf("Unable to read Database info. %s", err.Error()),
-			Code:    dbx.ErrDbAccess | dbx.ErrDbOperation,
+	toMigrate, err := m.pending(scripts[:last+1], infos)
+	if err != nil {
+		return err
+	}
+
+	if version != "" {
+		if err := m.revert(migrationRepo, appliedScripts(scripts[last+1:], infos), infos, lock); err != nil {
+			return err
 		}
 	}
 
-	objs, err := ioutil.ReadDir(m.DataDir)
+	for _, step := range toMigrate {
+		if m.Context != nil && m.Context.Err() != nil {
+			return &dbx.DbError{
+				Message: fmt.Sprintf("Migration aborted before running '%s'. %s", step.script.Id, m.Context.Err().Error()),
+				Code:    dbx.ErrMigrationFailed,
+			}
+		}
+		if err := lock.check("Migration", step.script.Id); err != nil {
+			return err
+		}
 
-	if err != nil {
-		return &dbx.DbError{
-			Message: fmt.Sprintf("Unable to access scripts path. %s", err.Error()),
-			Code:    dbx.ErrFileAccess,
+		if err := m.apply(migrationRepo, step); err != nil {
+			return err
 		}
 	}
 
-	foundNonMigrated := false
+	return nil
+}
 
-	var toMigrate []MigrationInfo
+// apply runs a script and records it as applied. If the database supports atomic transactions, the script and the
+// migration record are written in the same transaction, unless transactions are disabled for the script. If the
+// script fails, it is recorded with the 'failed' status and the error.
+func (m *Migrator) apply(migrationRepo string, step *migrationStep) error {
+	id := step.script.Id
+	run, transactional, err := m.runner(step.script, false)
+	if err != nil {
+		return err
+	}
 
-	for _, f := range streams.From(objs).
-		Filter(func(i interface{}) bool {
-			return strings.ToLower(i.(os.FileInfo).Name()) != "readme.md"
-		}).
-		OrderBy(func(a, b interface{}) int {
-			return strings.Compare(b.(os.FileInfo).Name(), a.(os.FileInfo).Name())
-		}, true).ToArray().([]os.FileInfo) {
+	info := MigrationInfo{
+		ScriptId:  id,
+		Hash:      step.hash,
+		Timestamp: time.Now(),
+		Status:    MigrationApplied,
+	}
 
-		if f.IsDir() {
-			continue
+	var runErr error
+	execute := func(db dbx.IDatabase) error {
+		runErr = run(db)
+		info.Duration = time.Since(info.Timestamp)
+		if runErr != nil {
+			return &dbx.DbError{
+				Message: fmt.Sprintf("Unable to run command '%s'. %s", id, runErr.Error()),
+				Code:    dbx.ErrDbOperation,
+			}
 		}
+		if err := m.saveInfo(db, migrationRepo, &info, step.existing != nil); err != nil {
+			return &dbx.DbError{
+				Message: fmt.Sprintf("Unable to save migration info for '%s'. %s", id, err.Error()),
+				Code:    dbx.ErrDbAccess | dbx.ErrDbOperation,
+			}
+		}
+		return nil
+	}
+
+	if transactional && m.transactional() {
+		err = m.Db.WithTransaction(execute)
+	} else {
+		err = execute(m.Db)
+	}
 
-		logger.Get().Info("Migrating file ", f.Name())
-		fullPath := paths.Combine(m.DataDir, f.Name())
-		hash, hashErr := computeHash(fullPath)
+	if runErr != nil {
+		info.Status = MigrationFailed
+		info.Error = runErr.Error()
+		if saveErr := m.saveInfo(m.Db, migrationRepo, &info, step.existing != nil); saveErr != nil {
+			logger.Get().Error(fmt.Sprintf("Unable to record the failure of '%s', %s", id, saveErr.Error()))
+		}
+	}
+	return err
+}
+
+// transactional indicates whether scripts are run in transactions
+func (m *Migrator) transactional() bool {
+	atomic, ok := m.Db.(dbx.IAtomicTransactions)
+	return !m.DisableTransactions && ok && atomic.AtomicTransactions()
+}
+
+// pending validates the previously migrated scripts and returns the scripts pending to be migrated.
+func (m *Migrator) pending(scripts []*migrationScript, infos map[string]*MigrationInfo) ([]*migrationStep, error) {
+	foundNonMigrated := false
+	var ret []*migrationStep
+
+	for _, s := range scripts {
+		logger.Get().Info("Migrating file ", s.Id)
+		hash, hashErr := s.hash()
 
 		if hashErr != nil {
-			return &dbx.DbError{
+			return nil, &dbx.DbError{
 				Message: fmt.Sprintf("Error computing hash for file '%s', aborting migration.", hashErr.Error()),
 				Code:    dbx.ErrMigrationFailed | dbx.ErrFileAccess,
 			}
 		}
 
-		// Contains the migration info
-		if inf := streams.From(infos).
-			Filter(
-				func(obj interface{}) bool {
-					return obj.(*MigrationInfo).ScriptId == f.Name()
-				}).
-			First(); inf != nil {
-
-			if foundNonMigrated && m.FailOnOrderMismatch {
-				return &dbx.DbError{
-					Message: fmt.Sprintf("Non-Migrated file found before '%s' which has been migrated. Order import failed, unable to proceed.", f.Name()),
-					Code:    dbx.ErrMigrationFailed,
-				}
-			}
+		info := infos[s.Id]
 
-			info := inf.(*MigrationInfo)
+		if info == nil || !info.IsApplied() {
+			foundNonMigrated = true
+			ret = append(ret, &migrationStep{script: s, hash: hash, existing: info})
+			continue
+		}
 
-			if info.Hash != hash {
-				return &dbx.DbError{
-					Message: fmt.Sprintf("File '%s' was previously migrated but hashes don't match.", f.Name()),
-					Code:    dbx.ErrMigrationFailed,
-				}
-			} else {
-				logger.Get().Info(fmt.Sprintf("File '%s' previously migrated, continuing", f.Name()))
+		if foundNonMigrated && m.FailOnOrderMismatch {
+			return nil, &dbx.DbError{
+				Message: fmt.Sprintf("Non-Migrated file found before '%s' which has been migrated. Order import failed, unable to proceed.", s.Id),
+				Code:    dbx.ErrMigrationFailed,
 			}
+		}
 
-			continue
-
-		} else {
-			foundNonMigrated = true
-			toMigrate = append(toMigrate, MigrationInfo{
-				ScriptId: f.Name(),
-				Hash:     hash,
-			})
+		if info.Hash != hash {
+			return nil, &dbx.DbError{
+				Message: fmt.Sprintf("File '%s' was previously migrated but hashes don't match.", s.Id),
+				Code:    dbx.ErrMigrationFailed,
+			}
 		}
-	}
 
-	executor := m.Db.Run
-	if m.ScriptExecutor != nil {
-		executor = m.ScriptExecutor
+		logger.Get().Info(fmt.Sprintf("File '%s' previously migrated, continuing", s.Id))
 	}
 
-	for _, info := range toMigrate {
-		fullPath := paths.Combine(m.DataDir, info.ScriptId)
-		if content, err := ioutil.ReadFile(fullPath); err != nil {
-			return &dbx.DbError{
-				Message: fmt.Sprintf("Unable to read data file '%s': %s", info.ScriptId, err.Error()),
-				Code:    dbx.ErrFileAccess,
-			}
-		} else {
+	return ret, nil
+}
 
-			script := string(content)
+func (m *Migrator) repoName() string {
+	if m.RepoIdSuffix != "" {
+		return MigrationRepo + "_" + m.RepoIdSuffix
+	}
+	return MigrationRepo
+}
 
-			if err := executor(script); err != nil {
-				return &dbx.DbError{
-					Message: fmt.Sprintf("Unable to run command '%s'. %s", info.ScriptId, err.Error()),
-					Code:    dbx.ErrDbOperation,
-				}
-			}
+// ensureRepo creates the migration repository if it does not exist. Otherwise, its schema is updated if the provider
+// supports it, since the repositories created by previous versions lack the status columns.
+func (m *Migrator) ensureRepo(migrationRepo string) error {
+	var err error
+	if !m.Db.HasRepo(migrationRepo) {
+		err = m.Db.CreateRepo(migrationRepo, &MigrationInfo{})
+	} else if updater, ok := m.Db.(dbx.IRepoSchemaUpdater); ok {
+		err = updater.UpdateRepo(migrationRepo, &MigrationInfo{})
+	}
+	if err != nil {
+		return &dbx.DbError{
+			Message: fmt.Sprintf("Unable to create the required migration repository. %s", err.Error()),
+			Code:    dbx.ErrDbAccess | dbx.ErrDbOperation,
+		}
+	}
+	return nil
+}
 
-			info.Timestamp = time.Now()
+// loadInfos reads the migration records by script id
+func (m *Migrator) loadInfos(migrationRepo string) (map[string]*MigrationInfo, error) {
+	var infos []*MigrationInfo
 
-			if err := m.Db.R(migrationRepo).Insert(info); err != nil {
-				return &dbx.DbError{
-					Message: fmt.Sprintf("Unable to save migration info for '%s'", info.ScriptId),
-					Code:    dbx.ErrDbAccess | dbx.ErrDbOperation,
-				}
-			}
+	if err := m.Db.R(migrationRepo).Where(bson.M{}).Sort("script_id").All(&infos); err != nil {
+		return nil, &dbx.DbError{
+			Message: fmt.Sprintf("Unable to read Database info. %s", err.Error()),
+			Code:    dbx.ErrDbAccess | dbx.ErrDbOperation,
 		}
 	}
 
-	return nil
+	ret := make(map[string]*MigrationInfo, len(infos))
+	for _, info := range infos {
+		ret[info.ScriptId] = info
+	}
+	return ret, nil
+}
+
+// saveInfo writes a migration record, inserting it if it does not exist. All the fields are provided on updates, since
+// some providers replace the whole record on update.
+func (m *Migrator) saveInfo(db dbx.IDatabase, migrationRepo string, info *MigrationInfo, exists bool) error {
+	if !exists {
+		return db.R(migrationRepo).Insert(*info)
+	}
+
+	var revertedAt interface{}
+	if info.RevertedAt != nil {
+		revertedAt = *info.RevertedAt
+	}
+	return db.R(migrationRepo).Where(dbx.Eq("script_id", info.ScriptId)).Update(map[string]interface{}{
+		"script_id":   info.ScriptId,
+		"hash":        info.Hash,
+		"timestamp":   info.Timestamp,
+		"status":      info.Status,
+		"reverted_at": revertedAt,
+		"error":       info.Error,
+		"duration":    info.Duration,
+	})
 }
 
-func computeHash(filePath string) (string, error) {
-	file, err := os.Open(filePath)
+func computeHash(fsys fs.FS, filePath string) (string, error) {
+	file, err := fsys.Open(filePath)
 
 	if err != nil {
 		return "", err
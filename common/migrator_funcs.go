@@ -0,0 +1,67 @@
+package common
+
+import (
+	"context"
+
+	"github.com/jucardi/go-db"
+)
+
+// MigrationFunc is a migration step implemented in Go. The context is the context of the migrator, or
+// `context.Background()` if the migrator does not have one. The database is the transaction the migration runs in, if
+// the migration runs in a transaction, otherwise the database of the migrator.
+type MigrationFunc func(ctx context.Context, db dbx.IDatabase) error
+
+// GoMigration is a migration implemented in Go, for data migrations that require application logic which can not be
+// expressed in a script.
+type GoMigration struct {
+	// Id identifies the migration in the migration repository. Go migrations are migrated in order by id along with the
+	// script files, so the id should follow the same naming as the files, e.g. '003_backfill_names'.
+	Id string
+
+	// Version is the version of the migration. Changing it after the migration ran makes following migrations fail,
+	// the same way a modified script does, so it should only change if the migration is rewritten.
+	//
+	// The code of a Go migration can not be hashed, so the hash recorded for it is computed from `Version` and
+	// `Checksum` only. Changes to the functions are not detected unless one of them changes as well.
+	Version string
+
+	// Checksum is an optional checksum of the migration provided by the caller, e.g. a hash of the data it writes or
+	// of its source, so changes to the migration are detected without bumping the version.
+	Checksum string
+
+	// Up runs the migration
+	Up MigrationFunc
+
+	// Down reverts the migration. Optional, the migration can not be reverted if not provided.
+	Down MigrationFunc
+
+	// NoTransaction disables running the migration in a transaction. See `Migrator.DisableTransactions`.
+	NoTransaction bool
+}
+
+// Register adds a migration implemented in Go. See `GoMigration` for details.
+//
+//    {id}       - The id of the migration, ordered with the names of the script files
+//    {version}  - The version of the migration
+//    {up}       - The function that runs the migration
+//    {down}     - (optional) The function that reverts the migration
+//
+func (m *Migrator) Register(id, version string, up MigrationFunc, down ...MigrationFunc) *Migrator {
+	migration := &GoMigration{
+		Id:      id,
+		Version: version,
+		Up:      up,
+	}
+	if len(down) > 0 {
+		migration.Down = down[0]
+	}
+	m.Migrations = append(m.Migrations, migration)
+	return m
+}
+
+func (m *Migrator) context() context.Context {
+	if m.Context != nil {
+		return m.Context
+	}
+	return context.Background()
+}
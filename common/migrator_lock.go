@@ -0,0 +1,200 @@
+package common
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/logger"
+)
+
+const (
+	lockRepoSuffix   = "_lock"
+	migrationLockId  = "migration"
+	defaultLockWait  = 5 * time.Minute
+	defaultLockLease = time.Minute
+)
+
+// lockPollInterval is the interval between attempts to acquire a lock held by another instance
+var lockPollInterval = time.Second
+
+// errLockLost is the renewal error when the lock record is no longer owned by the instance
+var errLockLost = errors.New("the lock is held by another instance")
+
+// rowsAffected is implemented by the queries which report the number of rows affected by an update, e.g. SQL queries,
+// which do not fail when the update does not match any row.
+type rowsAffected interface {
+	RowsAffected() int64
+}
+
+// migrationLock is a migration lock held by the migrator
+type migrationLock struct {
+	mu   sync.Mutex
+	lost error
+	stop func()
+}
+
+// release releases the lock
+func (l *migrationLock) release() {
+	if l.stop != nil {
+		l.stop()
+	}
+}
+
+// check returns an error if the lease of the lock could not be renewed, in which case the lock may have expired and
+// been acquired by another instance, so the migration must not continue.
+func (l *migrationLock) check(action, id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.lost == nil {
+		return nil
+	}
+	return &dbx.DbError{
+		Message: fmt.Sprintf("%s aborted before running '%s', unable to renew the migration lock. %s", action, id, l.lost.Error()),
+		Code:    dbx.ErrMigrationFailed,
+	}
+}
+
+// lock acquires the migration lock if `UseLock` is set. The returned lock must be released once the migration is done.
+//
+// The lock is a record with a unique id inserted in the lock repository, so only one instance succeeds inserting it.
+// The record has a lease that is renewed while the lock is held, an expired lease is removed by the instances
+// waiting for the lock. If the lease cannot be renewed, the next script to run fails.
+func (m *Migrator) lock() (*migrationLock, error) {
+	if !m.UseLock {
+		return &migrationLock{}, nil
+	}
+
+	lockRepo := m.repoName() + lockRepoSuffix
+	if !m.Db.HasRepo(lockRepo) {
+		if err := m.Db.CreateRepo(lockRepo, &MigrationLock{}); err != nil {
+			return nil, &dbx.DbError{
+				Message: fmt.Sprintf("Unable to create the required migration lock repository. %s", err.Error()),
+				Code:    dbx.ErrDbAccess | dbx.ErrDbOperation,
+			}
+		}
+	}
+	if err := m.Db.R(lockRepo).AddUniqueIndex("idx"+lockRepo+"_lock_id", "lock_id"); err != nil {
+		return nil, &dbx.DbError{
+			Message: fmt.Sprintf("Unable to create the required migration lock repository. %s", err.Error()),
+			Code:    dbx.ErrDbAccess | dbx.ErrDbOperation,
+		}
+	}
+
+	wait, lease := m.LockWait, m.LockLease
+	if wait <= 0 {
+		wait = defaultLockWait
+	}
+	if lease <= 0 {
+		lease = defaultLockLease
+	}
+
+	owner := lockOwner()
+	deadline := time.Now().Add(wait)
+
+	for {
+		now := time.Now()
+		if err := m.Db.R(lockRepo).Where(dbx.And(dbx.Eq("lock_id", migrationLockId), dbx.Lt("expires_at", now))).Delete(); err != nil && !isNotFound(err) {
+			logger.Get().Warn("Unable to remove an expired migration lock, ", err.Error())
+		}
+
+		err := m.Db.R(lockRepo).Insert(MigrationLock{LockId: migrationLockId, Owner: owner, ExpiresAt: now.Add(lease)})
+		if err == nil {
+			return m.holdLock(lockRepo, owner, lease), nil
+		}
+
+		if now.After(deadline) {
+			return nil, &dbx.DbError{
+				Message: fmt.Sprintf("Unable to acquire the migration lock within %s. %s", wait, err.Error()),
+				Code:    dbx.ErrMigrationFailed,
+			}
+		}
+
+		logger.Get().Info("Waiting for the migration lock held by another instance")
+
+		var done <-chan struct{}
+		if m.Context != nil {
+			done = m.Context.Done()
+		}
+		select {
+		case <-time.After(lockPollInterval):
+		case <-done:
+			return nil, &dbx.DbError{
+				Message: fmt.Sprintf("Migration aborted while waiting for the migration lock. %s", m.Context.Err().Error()),
+				Code:    dbx.ErrMigrationFailed,
+			}
+		}
+	}
+}
+
+// holdLock renews the lease of the lock until it is released. A renewal failure is recorded in the lock, including a
+// renewal that does not match the lock record, since the lease expired and the lock was taken by another instance.
+func (m *Migrator) holdLock(lockRepo, owner string, lease time.Duration) *migrationLock {
+	l := &migrationLock{}
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	condition := dbx.And(dbx.Eq("lock_id", migrationLockId), dbx.Eq("owner", owner))
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				q := m.Db.R(lockRepo).Where(condition)
+				err := q.Update(map[string]interface{}{
+					"lock_id":    migrationLockId,
+					"owner":      owner,
+					"expires_at": time.Now().Add(lease),
+				})
+				if r, ok := q.(rowsAffected); ok && err == nil && r.RowsAffected() == 0 {
+					err = errLockLost
+				}
+				if err != nil {
+					logger.Get().Error("Unable to renew the migration lock, ", err.Error())
+					l.mu.Lock()
+					if l.lost == nil {
+						l.lost = err
+					}
+					l.mu.Unlock()
+				}
+			}
+		}
+	}()
+
+	l.stop = func() {
+		close(stop)
+		<-stopped
+		if err := m.Db.R(lockRepo).Where(condition).Delete(); err != nil {
+			logger.Get().Error("Unable to release the migration lock, ", err.Error())
+		}
+	}
+	return l
+}
+
+// isNotFound indicates whether the error reports that no record matched the query, which is returned by the MongoDB
+// and in-memory providers when deleting or updating through a query.
+func isNotFound(err error) bool {
+	if dbErr, ok := err.(*dbx.DbError); ok {
+		return dbErr.IsNotFound()
+	}
+	// mgo.ErrNotFound, which is not referenced to avoid depending on the mgo package
+	return err.Error() == "not found"
+}
+
+// lockOwner returns a unique identifier for the instance acquiring the lock
+func lockOwner() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
@@ -0,0 +1,122 @@
+package common
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jucardi/go-db"
+)
+
+// MigrationPlan is the report of what a migration would do, obtained by `Migrator.Plan`
+type MigrationPlan struct {
+	// Pending are the scripts that would be migrated, in the order they would run
+	Pending []*PlannedScript
+
+	// Applied are the scripts previously migrated which hash matches the script
+	Applied []*PlannedScript
+
+	// HashMismatches are the scripts previously migrated which content changed since they were migrated. A migration
+	// fails if any is found.
+	HashMismatches []*PlannedScript
+
+	// OrderViolations are the scripts previously migrated found after scripts that have not been migrated, e.g. when a
+	// script is added between previously migrated scripts. A migration fails if any is found and
+	// `FailOnOrderMismatch` is set.
+	OrderViolations []*PlannedScript
+
+	// FailOnOrderMismatch is the configuration of the migrator used to build the plan
+	FailOnOrderMismatch bool
+}
+
+// PlannedScript is a script included in a migration plan
+type PlannedScript struct {
+	// ScriptId is the file name of the script
+	ScriptId string
+
+	// Hash is the hash of the current content of the script
+	Hash string
+
+	// PreviousHash is the hash recorded when the script was migrated. Empty if it was never migrated.
+	PreviousHash string
+
+	// MigratedAt is the time the script was migrated. Nil if it was never migrated.
+	MigratedAt *time.Time
+
+	// LastError is the error of the last attempt to migrate the script, if it failed
+	LastError string
+}
+
+// CanMigrate indicates whether a migration would succeed the validations of previously migrated scripts
+func (p *MigrationPlan) CanMigrate() bool {
+	return len(p.HashMismatches) == 0 && (!p.FailOnOrderMismatch || len(p.OrderViolations) == 0)
+}
+
+// Plan reports what `Migrate` would do without running any script or writing to the migration repository: the
+// scripts pending to be migrated, the scripts already migrated, and the hash mismatches and order violations that would
+// make the migration fail.
+func (m *Migrator) Plan() (*MigrationPlan, error) {
+	infos, scripts, err := m.readState()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &MigrationPlan{FailOnOrderMismatch: m.FailOnOrderMismatch}
+	foundNonMigrated := false
+
+	for _, s := range scripts {
+		hash, hashErr := s.hash()
+		if hashErr != nil {
+			return nil, &dbx.DbError{
+				Message: fmt.Sprintf("Error computing hash for file '%s'. %s", s.Id, hashErr.Error()),
+				Code:    dbx.ErrFileAccess,
+			}
+		}
+
+		planned := &PlannedScript{ScriptId: s.Id, Hash: hash}
+		info := infos[s.Id]
+
+		if info == nil || !info.IsApplied() {
+			if info != nil && info.Status == MigrationFailed {
+				planned.LastError = info.Error
+			}
+			foundNonMigrated = true
+			plan.Pending = append(plan.Pending, planned)
+			continue
+		}
+
+		timestamp := info.Timestamp
+		planned.PreviousHash = info.Hash
+		planned.MigratedAt = &timestamp
+
+		if foundNonMigrated {
+			plan.OrderViolations = append(plan.OrderViolations, planned)
+		}
+		if info.Hash != hash {
+			plan.HashMismatches = append(plan.HashMismatches, planned)
+		} else {
+			plan.Applied = append(plan.Applied, planned)
+		}
+	}
+
+	return plan, nil
+}
+
+// readState reads the migration records and the scripts without writing to the database. The migration records are
+// empty if the migration repository does not exist.
+func (m *Migrator) readState() (map[string]*MigrationInfo, []*migrationScript, error) {
+	migrationRepo := m.repoName()
+	infos := map[string]*MigrationInfo{}
+
+	if m.Db.HasRepo(migrationRepo) {
+		var err error
+		if infos, err = m.loadInfos(migrationRepo); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	scripts, err := m.loadScripts()
+	if err != nil {
+		return nil, nil, err
+	}
+	return infos, scripts, nil
+}
@@ -0,0 +1,253 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/logger"
+)
+
+const auditRepoSuffix = "_audit"
+
+// Repair updates the recorded hash of migrated scripts which content changed after they were migrated, so following
+// migrations do not fail. Intended for intentional edits of migrated scripts, e.g. fixing a comment. The scripts are
+// not run again. An audit record with the previous hash is stored for each repaired script in the migration audit
+// repository.
+//
+//    {confirm}    - Must be true to repair the scripts. If false, the scripts that would be repaired are returned
+//                   without modifying the database, so they can be reviewed before confirming.
+//    {scriptIds}  - (optional) The ids of the scripts to repair. If not provided, all the modified scripts are repaired.
+//
+// Returns the ids of the repaired scripts.
+func (m *Migrator) Repair(confirm bool, scriptIds ...string) ([]string, error) {
+	if !confirm {
+		infos, scripts, err := m.readState()
+		if err != nil {
+			return nil, err
+		}
+		modified, _, err := modifiedScripts(scripts, infos, scriptIds)
+		return ids(modified), err
+	}
+
+	migrationRepo := m.repoName()
+
+	if err := m.ensureRepo(migrationRepo); err != nil {
+		return nil, err
+	}
+
+	lock, err := m.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.release()
+
+	infos, err := m.loadInfos(migrationRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	scripts, err := m.loadScripts()
+	if err != nil {
+		return nil, err
+	}
+
+	modified, hashes, err := modifiedScripts(scripts, infos, scriptIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var repaired []string
+	for _, s := range modified {
+		logger.Get().Info(fmt.Sprintf("Repairing the hash of '%s'", s.Id))
+		info := *infos[s.Id]
+		info.Hash = hashes[s.Id]
+
+		if err := m.recordWithAudit(migrationRepo, AuditRepair, &info, infos[s.Id].Hash, true); err != nil {
+			return repaired, err
+		}
+		repaired = append(repaired, s.Id)
+	}
+	return repaired, nil
+}
+
+// Baseline records scripts as migrated without running them, for databases which schema was created before using the
+// migrator. Scripts already migrated are not modified. An audit record is stored for each recorded script in the
+// migration audit repository.
+//
+//    {upTo}  - The id or version of the last script to record, see `MigrateTo`. If empty, all the scripts are recorded.
+//
+// Returns the ids of the recorded scripts.
+func (m *Migrator) Baseline(upTo string) ([]string, error) {
+	migrationRepo := m.repoName()
+
+	if err := m.ensureRepo(migrationRepo); err != nil {
+		return nil, err
+	}
+
+	lock, err := m.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.release()
+
+	infos, err := m.loadInfos(migrationRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	scripts, err := m.loadScripts()
+	if err != nil {
+		return nil, err
+	}
+
+	last := len(scripts) - 1
+	if upTo != "" {
+		if last = findScript(scripts, upTo); last < 0 {
+			return nil, &dbx.DbError{
+				Message: fmt.Sprintf("Version '%s' was not found in the migration scripts.", upTo),
+				Code:    dbx.ErrMigrationFailed,
+			}
+		}
+	}
+
+	var recorded []string
+	for _, s := range scripts[:last+1] {
+		existing := infos[s.Id]
+		if existing != nil && existing.IsApplied() {
+			continue
+		}
+
+		hash, err := s.hash()
+		if err != nil {
+			return recorded, &dbx.DbError{
+				Message: fmt.Sprintf("Error computing hash for file '%s'. %s", s.Id, err.Error()),
+				Code:    dbx.ErrFileAccess,
+			}
+		}
+
+		info := MigrationInfo{
+			ScriptId:  s.Id,
+			Hash:      hash,
+			Timestamp: time.Now(),
+			Status:    MigrationApplied,
+		}
+		oldHash := ""
+		if existing != nil {
+			oldHash = existing.Hash
+		}
+		if err := m.recordWithAudit(migrationRepo, AuditBaseline, &info, oldHash, existing != nil); err != nil {
+			return recorded, err
+		}
+		recorded = append(recorded, s.Id)
+	}
+	return recorded, nil
+}
+
+// recordWithAudit saves a migration record along with its audit record, in a transaction if the database supports it.
+func (m *Migrator) recordWithAudit(migrationRepo, action string, info *MigrationInfo, oldHash string, exists bool) error {
+	auditRepo := migrationRepo + auditRepoSuffix
+	if !m.Db.HasRepo(auditRepo) {
+		if err := m.Db.CreateRepo(auditRepo, &MigrationAudit{}); err != nil {
+			return &dbx.DbError{
+				Message: fmt.Sprintf("Unable to create the required migration audit repository. %s", err.Error()),
+				Code:    dbx.ErrDbAccess | dbx.ErrDbOperation,
+			}
+		}
+	}
+
+	audit := MigrationAudit{
+		Action:    action,
+		ScriptId:  info.ScriptId,
+		OldHash:   oldHash,
+		NewHash:   info.Hash,
+		Operator:  operator(),
+		Timestamp: time.Now(),
+	}
+
+	execute := func(db dbx.IDatabase) error {
+		if err := m.saveInfo(db, migrationRepo, info, exists); err != nil {
+			return &dbx.DbError{
+				Message: fmt.Sprintf("Unable to save migration info for '%s'. %s", info.ScriptId, err.Error()),
+				Code:    dbx.ErrDbAccess | dbx.ErrDbOperation,
+			}
+		}
+		if err := db.R(auditRepo).Insert(audit); err != nil {
+			return &dbx.DbError{
+				Message: fmt.Sprintf("Unable to save the audit record for '%s'. %s", info.ScriptId, err.Error()),
+				Code:    dbx.ErrDbAccess | dbx.ErrDbOperation,
+			}
+		}
+		return nil
+	}
+
+	if m.transactional() {
+		return m.Db.WithTransaction(execute)
+	}
+	return execute(m.Db)
+}
+
+// modifiedScripts returns the migrated scripts which hash changed, and their current hashes. If script ids are
+// provided, only those scripts are returned, and an error is returned if any of them is not migrated.
+func modifiedScripts(scripts []*migrationScript, infos map[string]*MigrationInfo, scriptIds []string) ([]*migrationScript, map[string]string, error) {
+	requested := map[string]bool{}
+	for _, id := range scriptIds {
+		requested[id] = true
+	}
+
+	var ret []*migrationScript
+	hashes := map[string]string{}
+
+	for _, s := range scripts {
+		info := infos[s.Id]
+		if info == nil || !info.IsApplied() || len(scriptIds) > 0 && !requested[s.Id] {
+			continue
+		}
+		delete(requested, s.Id)
+
+		hash, err := s.hash()
+		if err != nil {
+			return nil, nil, &dbx.DbError{
+				Message: fmt.Sprintf("Error computing hash for file '%s'. %s", s.Id, err.Error()),
+				Code:    dbx.ErrFileAccess,
+			}
+		}
+		if hash != info.Hash {
+			ret = append(ret, s)
+			hashes[s.Id] = hash
+		}
+	}
+
+	if len(requested) > 0 {
+		var missing []string
+		for id := range requested {
+			missing = append(missing, id)
+		}
+		sort.Strings(missing)
+		return nil, nil, &dbx.DbError{
+			Message: fmt.Sprintf("Scripts not found or not migrated: %s", strings.Join(missing, ", ")),
+			Code:    dbx.ErrMigrationFailed,
+		}
+	}
+	return ret, hashes, nil
+}
+
+func ids(scripts []*migrationScript) (ret []string) {
+	for _, s := range scripts {
+		ret = append(ret, s.Id)
+	}
+	return
+}
+
+// operator identifies the user and host performing a manual operation
+func operator() string {
+	host, _ := os.Hostname()
+	if u, err := user.Current(); err == nil {
+		return u.Username + "@" + host
+	}
+	return host
+}
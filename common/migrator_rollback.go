@@ -0,0 +1,146 @@
+package common
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/logger"
+)
+
+// Rollback reverts the last applied scripts by running their down scripts in reverse order. The scripts are marked as
+// reverted in the migration repository, and are migrated again by the next call to `Migrate`.
+//
+//    {steps}  - The amount of applied scripts to revert
+//
+// The rollback fails before reverting any script if one of the scripts to revert does not have a down script.
+func (m *Migrator) Rollback(steps int) error {
+	if steps <= 0 {
+		return nil
+	}
+
+	migrationRepo := m.repoName()
+
+	if err := m.ensureRepo(migrationRepo); err != nil {
+		return err
+	}
+
+	lock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+
+	infos, err := m.loadInfos(migrationRepo)
+	if err != nil {
+		return err
+	}
+
+	scripts, err := m.loadScripts()
+	if err != nil {
+		return err
+	}
+
+	applied := appliedScripts(scripts, infos)
+	if err := checkMissingScripts(scripts, infos); err != nil {
+		return err
+	}
+
+	if steps > len(applied) {
+		steps = len(applied)
+	}
+	return m.revert(migrationRepo, applied[len(applied)-steps:], infos, lock)
+}
+
+// revert runs the down scripts of the provided scripts in reverse order, and marks their records as reverted.
+func (m *Migrator) revert(migrationRepo string, scripts []*migrationScript, infos map[string]*MigrationInfo, lock *migrationLock) error {
+	for _, s := range scripts {
+		if !s.reversible() {
+			return &dbx.DbError{
+				Message: fmt.Sprintf("Script '%s' cannot be reverted, a down script was not found", s.Id),
+				Code:    dbx.ErrMigrationFailed,
+			}
+		}
+	}
+
+	for i := len(scripts) - 1; i >= 0; i-- {
+		s := scripts[i]
+		if m.Context != nil && m.Context.Err() != nil {
+			return &dbx.DbError{
+				Message: fmt.Sprintf("Rollback aborted before reverting '%s'. %s", s.Id, m.Context.Err().Error()),
+				Code:    dbx.ErrMigrationFailed,
+			}
+		}
+		if err := lock.check("Rollback", s.Id); err != nil {
+			return err
+		}
+
+		logger.Get().Info("Reverting file ", s.Id)
+
+		run, transactional, err := m.runner(s, true)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		info := *infos[s.Id]
+		info.Status = MigrationReverted
+		info.RevertedAt = &now
+
+		execute := func(db dbx.IDatabase) error {
+			if err := run(db); err != nil {
+				return &dbx.DbError{
+					Message: fmt.Sprintf("Unable to revert '%s'. %s", s.Id, err.Error()),
+					Code:    dbx.ErrDbOperation,
+				}
+			}
+			if err := m.saveInfo(db, migrationRepo, &info, true); err != nil {
+				return &dbx.DbError{
+					Message: fmt.Sprintf("Unable to save migration info for '%s'. %s", s.Id, err.Error()),
+					Code:    dbx.ErrDbAccess | dbx.ErrDbOperation,
+				}
+			}
+			return nil
+		}
+
+		if transactional && m.transactional() {
+			err = m.Db.WithTransaction(execute)
+		} else {
+			err = execute(m.Db)
+		}
+		if err != nil {
+			return err
+		}
+		infos[s.Id] = &info
+	}
+
+	return nil
+}
+
+// appliedScripts returns the scripts that are currently applied, in the order of the provided list
+func appliedScripts(scripts []*migrationScript, infos map[string]*MigrationInfo) (ret []*migrationScript) {
+	for _, s := range scripts {
+		if info := infos[s.Id]; info != nil && info.IsApplied() {
+			ret = append(ret, s)
+		}
+	}
+	return
+}
+
+// checkMissingScripts fails if a script is applied according to the migration repository but it is not present in
+// the data dir, since the scripts applied after it could not be reverted safely.
+func checkMissingScripts(scripts []*migrationScript, infos map[string]*MigrationInfo) error {
+	found := make(map[string]bool, len(scripts))
+	for _, s := range scripts {
+		found[s.Id] = true
+	}
+	for id, info := range infos {
+		if info.IsApplied() && !found[id] {
+			return &dbx.DbError{
+				Message: fmt.Sprintf("Script '%s' was migrated but it was not found in the scripts path.", id),
+				Code:    dbx.ErrMigrationFailed,
+			}
+		}
+	}
+	return nil
+}
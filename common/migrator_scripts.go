@@ -0,0 +1,269 @@
+package common
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/logger"
+)
+
+const (
+	upSuffix   = ".up"
+	downSuffix = ".down"
+)
+
+// migrationScript is a migration script found in the data dir.
+type migrationScript struct {
+	// Id is the file name of the script, used to track it in the migration repository. For paired scripts, it is the
+	// name of the up script.
+	Id string
+
+	// Version is the leading number of the file name, or the file name without extensions if it does not start with
+	// a number. Used by `MigrateTo`.
+	Version string
+
+	// Path is the location of the script in its source file system
+	Path string
+
+	// DownPath is the location of the down script used to revert the script. Empty if the script is not reversible.
+	DownPath string
+
+	// Migration is the migration implemented in Go, if the script is not a file
+	Migration *GoMigration
+
+	// source is the file system where the script is located
+	source fs.FS
+}
+
+// hash returns the hash used to verify the script was not modified after it was migrated
+func (s *migrationScript) hash() (string, error) {
+	if s.Migration != nil {
+		content := s.Migration.Version
+		if s.Migration.Checksum != "" {
+			content += "\x00" + s.Migration.Checksum
+		}
+		sum := md5.Sum([]byte(content))
+		return hex.EncodeToString(sum[:]), nil
+	}
+	return computeHash(s.source, s.Path)
+}
+
+// reversible indicates whether the script can be reverted
+func (s *migrationScript) reversible() bool {
+	if s.Migration != nil {
+		return s.Migration.Down != nil
+	}
+	return s.DownPath != ""
+}
+
+// runner returns the function that runs the script, or its down script if 'down' is true, using the provided database.
+// Also indicates whether the script may run in a transaction.
+func (m *Migrator) runner(s *migrationScript, down bool) (func(db dbx.IDatabase) error, bool, error) {
+	if s.Migration != nil {
+		fn := s.Migration.Up
+		if down {
+			fn = s.Migration.Down
+		}
+		return func(db dbx.IDatabase) error {
+			return fn(m.context(), db)
+		}, !s.Migration.NoTransaction, nil
+	}
+
+	p := s.Path
+	if down {
+		p = s.DownPath
+	}
+	content, err := fs.ReadFile(s.source, p)
+	if err != nil {
+		return nil, false, &dbx.DbError{
+			Message: fmt.Sprintf("Unable to read data file '%s': %s", path.Base(p), err.Error()),
+			Code:    dbx.ErrFileAccess,
+		}
+	}
+
+	script := string(content)
+	transactional := m.ScriptExecutor == nil && !strings.Contains(script, NoTransactionMarker)
+
+	return func(db dbx.IDatabase) error {
+		if m.ScriptExecutor != nil {
+			return m.ScriptExecutor(script)
+		}
+		return db.Run(script)
+	}, transactional, nil
+}
+
+// parseScriptName splits a file name into the key used to pair up and down scripts and its direction. Files without
+// the '.up' or '.down' suffix before the extension are up scripts.
+func parseScriptName(name string) (key string, down bool) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+
+	switch {
+	case strings.HasSuffix(stem, downSuffix):
+		return strings.TrimSuffix(stem, downSuffix) + ext, true
+	case strings.HasSuffix(stem, upSuffix):
+		return strings.TrimSuffix(stem, upSuffix) + ext, false
+	}
+	return name, false
+}
+
+// scriptVersion returns the version of a script by its file name.
+func scriptVersion(name string) string {
+	key, _ := parseScriptName(name)
+	if i := strings.IndexFunc(key, func(r rune) bool { return !unicode.IsDigit(r) }); i != 0 {
+		if i < 0 {
+			return key
+		}
+		return key[:i]
+	}
+	return strings.TrimSuffix(key, filepath.Ext(key))
+}
+
+// loadScripts reads the scripts in the data dir, sorted by id. Down scripts are attached to their up script.
+//
+// The data dir may be empty if the migrator only has Go migrations.
+func (m *Migrator) loadScripts() ([]*migrationScript, error) {
+	fsys, root := m.source()
+
+	var files []scriptFile
+	if m.DataDir != "" || m.FS != nil || len(m.Migrations) == 0 {
+		var err error
+		if files, err = m.listScripts(fsys, root, ""); err != nil {
+			// errors of the operating system file system report paths relative to the data dir
+			if pathErr, ok := err.(*fs.PathError); ok && m.FS == nil {
+				pathErr.Path = filepath.Join(m.DataDir, filepath.FromSlash(pathErr.Path))
+			}
+			return nil, &dbx.DbError{
+				Message: fmt.Sprintf("Unable to access scripts path. %s", err.Error()),
+				Code:    dbx.ErrFileAccess,
+			}
+		}
+	}
+
+	var scripts []*migrationScript
+	byKey := map[string]*migrationScript{}
+	downs := map[string]scriptFile{}
+
+	for _, f := range files {
+		key, down := parseScriptName(f.id)
+		if down {
+			downs[key] = f
+			continue
+		}
+		s := &migrationScript{
+			Id:      f.id,
+			Version: scriptVersion(path.Base(f.id)),
+			Path:    f.path,
+			source:  fsys,
+		}
+		scripts = append(scripts, s)
+		byKey[key] = s
+	}
+
+	for _, migration := range m.Migrations {
+		if migration.Id == "" || migration.Up == nil {
+			return nil, &dbx.DbError{
+				Message: "Go migrations require an id and an up function.",
+				Code:    dbx.ErrMigrationFailed,
+			}
+		}
+		key, _ := parseScriptName(migration.Id)
+		if byKey[key] != nil {
+			return nil, &dbx.DbError{
+				Message: fmt.Sprintf("Migration '%s' is defined more than once.", migration.Id),
+				Code:    dbx.ErrMigrationFailed,
+			}
+		}
+		s := &migrationScript{
+			Id:        migration.Id,
+			Version:   scriptVersion(migration.Id),
+			Migration: migration,
+		}
+		scripts = append(scripts, s)
+		byKey[key] = s
+	}
+
+	for key, f := range downs {
+		if s, ok := byKey[key]; ok && s.Migration == nil {
+			s.DownPath = f.path
+		} else {
+			logger.Get().Warn(fmt.Sprintf("Down script '%s' does not have a matching up script, ignoring", f.id))
+		}
+	}
+
+	sort.Slice(scripts, func(i, j int) bool {
+		return scripts[i].Id < scripts[j].Id
+	})
+	return scripts, nil
+}
+
+// scriptFile is a file found in the data dir
+type scriptFile struct {
+	// id is the path of the file relative to the data dir, using '/' as separator
+	id string
+
+	// path is the location of the file in the file system of the migrator
+	path string
+}
+
+// listScripts lists the script files in the provided dir. Subdirectories are listed if `Recursive` is set, otherwise
+// they are skipped with a warning, since scripts placed in them would never be migrated.
+func (m *Migrator) listScripts(fsys fs.FS, dir, rel string) ([]scriptFile, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []scriptFile
+	for _, e := range entries {
+		id := path.Join(rel, e.Name())
+
+		if e.IsDir() {
+			if !m.Recursive {
+				logger.Get().Warn(fmt.Sprintf("Skipping directory '%s', subdirectories are only migrated if Recursive is set", id))
+				continue
+			}
+			files, err := m.listScripts(fsys, path.Join(dir, e.Name()), id)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, files...)
+			continue
+		}
+
+		if strings.ToLower(e.Name()) != "readme.md" {
+			ret = append(ret, scriptFile{id: id, path: path.Join(dir, e.Name())})
+		}
+	}
+	return ret, nil
+}
+
+// source returns the file system where the scripts are located and the location of the data dir in it.
+func (m *Migrator) source() (fs.FS, string) {
+	if m.FS == nil {
+		return os.DirFS(m.DataDir), "."
+	}
+	if m.DataDir == "" {
+		return m.FS, "."
+	}
+	return m.FS, m.DataDir
+}
+
+// findScript returns the index of the last script which version or id matches the provided version.
+func findScript(scripts []*migrationScript, version string) int {
+	for i := len(scripts) - 1; i >= 0; i-- {
+		if scripts[i].Version == version || scripts[i].Id == version {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,146 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jucardi/go-db"
+)
+
+const (
+	// StateApplied is the state of a script that is migrated and was not modified since
+	StateApplied = "applied"
+
+	// StatePending is the state of a script that has not been migrated, or was reverted
+	StatePending = "pending"
+
+	// StateFailed is the state of a script which last attempt to migrate failed
+	StateFailed = "failed"
+
+	// StateModified is the state of a script that was modified after it was migrated
+	StateModified = "modified"
+
+	// StateMissing is the state of a script that was migrated but is no longer present in the scripts
+	StateMissing = "missing"
+)
+
+// ScriptStatus is the status of a migration script, obtained by `Migrator.Status`
+type ScriptStatus struct {
+	// ScriptId is the id of the script
+	ScriptId string `json:"script_id"`
+
+	// State is the state of the script, see `StateApplied`, `StatePending`, `StateFailed`, `StateModified` and
+	// `StateMissing`
+	State string `json:"state"`
+
+	// Hash is the hash of the current content of the script. Empty if the script is missing.
+	Hash string `json:"hash,omitempty"`
+
+	// AppliedHash is the hash of the script when it was migrated. Empty if it was never migrated.
+	AppliedHash string `json:"applied_hash,omitempty"`
+
+	// AppliedAt is the time the script was last run. Nil if it was never run.
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+
+	// Duration is the time it took to run the script the last time it was run
+	Duration time.Duration `json:"duration,omitempty"`
+
+	// Error is the error of the last attempt to run the script, if it failed
+	Error string `json:"error,omitempty"`
+}
+
+// Status returns the status of every known script, the scripts found in the data dir and the Go migrations, along with
+// the scripts recorded in the migration repository that are no longer present. The result is sorted by script id.
+// Does not write to the database.
+func (m *Migrator) Status() ([]*ScriptStatus, error) {
+	infos, scripts, err := m.readState()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*ScriptStatus
+	found := map[string]bool{}
+
+	for _, s := range scripts {
+		found[s.Id] = true
+		hash, err := s.hash()
+		if err != nil {
+			return nil, &dbx.DbError{
+				Message: fmt.Sprintf("Error computing hash for file '%s'. %s", s.Id, err.Error()),
+				Code:    dbx.ErrFileAccess,
+			}
+		}
+
+		status := newScriptStatus(s.Id, infos[s.Id])
+		status.Hash = hash
+
+		switch {
+		case status.State != StateApplied:
+		case status.AppliedHash != hash:
+			status.State = StateModified
+		}
+		ret = append(ret, status)
+	}
+
+	for id, info := range infos {
+		if !found[id] {
+			status := newScriptStatus(id, info)
+			status.State = StateMissing
+			ret = append(ret, status)
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].ScriptId < ret[j].ScriptId
+	})
+	return ret, nil
+}
+
+func newScriptStatus(id string, info *MigrationInfo) *ScriptStatus {
+	ret := &ScriptStatus{ScriptId: id, State: StatePending}
+	if info == nil {
+		return ret
+	}
+
+	timestamp := info.Timestamp
+	ret.AppliedHash = info.Hash
+	ret.AppliedAt = &timestamp
+	ret.Duration = info.Duration
+	ret.Error = info.Error
+
+	switch {
+	case info.IsApplied():
+		ret.State = StateApplied
+	case info.Status == MigrationFailed:
+		ret.State = StateFailed
+	default:
+		ret.AppliedHash, ret.AppliedAt, ret.Duration = "", nil, 0
+	}
+	return ret
+}
+
+// FormatStatus writes the provided statuses as a human readable table
+func FormatStatus(w io.Writer, statuses []*ScriptStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "SCRIPT\tSTATE\tAPPLIED AT\tDURATION\tHASH"); err != nil {
+		return err
+	}
+
+	for _, s := range statuses {
+		appliedAt, duration, hash := "-", "-", s.Hash
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+			duration = s.Duration.Round(time.Millisecond).String()
+		}
+		if hash == "" {
+			hash = s.AppliedHash
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.ScriptId, s.State, appliedAt, duration, hash); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
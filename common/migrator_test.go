@@ -1,6 +1,8 @@
 package common
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"github.com/jucardi/go-db"
 	"github.com/jucardi/go-db/logger"
@@ -8,7 +10,11 @@ import (
 	"github.com/jucardi/go-testx/mock"
 	. "github.com/jucardi/go-testx/testx"
 	"github.com/jucardi/go-logger-lib/log"
+	"os"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 const migrationPath = "./test_assets/db_migration"
@@ -131,14 +137,21 @@ func TestRunFailed(t *testing.T) {
 	db, repo, q := testutils.MockAll()
 	Convey("Migrate Failed - Script failed to run", t, func() {
 		db.WhenReturn("Run", errors.New("some error"))
+		var failed MigrationInfo
+		repo.When("Insert", func(args ...interface{}) []interface{} {
+			failed = args[0].(MigrationInfo)
+			return mock.MakeReturn(nil)
+		})
 
 		err := Migrate(migrationPath, db, true)
 		ShouldError(err)
 		ShouldEqual("Unable to run command 'script_001.js'. some error", err.Error())
+		ShouldEqual(MigrationFailed, failed.Status)
+		ShouldEqual("some error", failed.Error)
 
 		ShouldEqual(1, repo.Times("Where"))
-		ShouldEqual(0, repo.Times("Insert"))
-		ShouldEqual(1, db.Times("R"))
+		ShouldEqual(1, repo.Times("Insert"))
+		ShouldEqual(2, db.Times("R"))
 		ShouldEqual(1, db.Times("Run"))
 		ShouldEqual(1, q.Times("Sort"))
 		ShouldEqual(1, q.Times("All"))
@@ -153,7 +166,7 @@ func TestInsertFailed(t *testing.T) {
 
 		err := Migrate(migrationPath, db, true)
 		ShouldError(err)
-		ShouldEqual("Unable to save migration info for 'script_001.js'", err.Error())
+		ShouldEqual("Unable to save migration info for 'script_001.js'. some error", err.Error())
 
 		ShouldEqual(1, repo.Times("Where"))
 		ShouldEqual(1, repo.Times("Insert"))
@@ -164,3 +177,372 @@ func TestInsertFailed(t *testing.T) {
 	})
 
 }
+
+func TestMigrateContextCancelled(t *testing.T) {
+	db, repo, q := testutils.MockAll()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	Convey("Migrate Failed - Context done before running the scripts", t, func() {
+		migrator := &Migrator{
+			Db:                  db,
+			DataDir:             migrationPath,
+			FailOnOrderMismatch: true,
+			Context:             ctx,
+		}
+		err := migrator.Migrate()
+		ShouldError(err)
+		ex, ok := err.(*dbx.DbError)
+		ShouldBeTrue(ok)
+		ShouldBeTrue(ex.Is(dbx.ErrMigrationFailed))
+		ShouldEqual("Migration aborted before running 'script_001.js'. context canceled", err.Error())
+
+		ShouldEqual(0, repo.Times("Insert"))
+		ShouldEqual(0, db.Times("Run"))
+		ShouldEqual(1, q.Times("All"))
+	})
+}
+
+const rollbackPath = "./test_assets/db_rollback"
+
+func mockApplied(q *testutils.QueryMock, status map[string]string) {
+	q.When("All", func(args ...interface{}) []interface{} {
+		list := args[0].(*[]*MigrationInfo)
+		for _, id := range []string{"001_collection.up.js", "002_data.up.js", "003_index.js"} {
+			if s, ok := status[id]; ok {
+				hash, _ := computeHash(os.DirFS(rollbackPath), id)
+				*list = append(*list, &MigrationInfo{ScriptId: id, Hash: hash, Status: s})
+			}
+		}
+		return mock.MakeReturn(nil)
+	})
+}
+
+func TestRollbackSuccess(t *testing.T) {
+	db, repo, q := testutils.MockAll()
+	Convey("Rollback Success", t, func() {
+		mockApplied(q, map[string]string{
+			"001_collection.up.js": MigrationApplied,
+			"002_data.up.js":       MigrationApplied,
+		})
+		migrator := &Migrator{Db: db, DataDir: rollbackPath}
+
+		ShouldBeNil(migrator.Rollback(5))
+		ShouldEqual(2, db.Times("Run"))
+		ShouldEqual(2, q.Times("Update"))
+		ShouldEqual(0, repo.Times("Insert"))
+	})
+	Convey("Rollback Failed - Failed to update migration data", t, func() {
+		q.WhenReturn("Update", errors.New("some error"))
+		migrator := &Migrator{Db: db, DataDir: rollbackPath}
+
+		err := migrator.Rollback(1)
+		ShouldError(err)
+		ShouldEqual("Unable to save migration info for '002_data.up.js'. some error", err.Error())
+	})
+}
+
+func TestRollbackMissingDownScript(t *testing.T) {
+	db, _, q := testutils.MockAll()
+	Convey("Rollback Failed - Script without a down script", t, func() {
+		mockApplied(q, map[string]string{
+			"001_collection.up.js": MigrationApplied,
+			"002_data.up.js":       MigrationApplied,
+			"003_index.js":         MigrationApplied,
+		})
+		migrator := &Migrator{Db: db, DataDir: rollbackPath}
+
+		err := migrator.Rollback(2)
+		ShouldError(err)
+		ShouldEqual("Script '003_index.js' cannot be reverted, a down script was not found", err.Error())
+		ShouldEqual(0, db.Times("Run"))
+		ShouldEqual(0, q.Times("Update"))
+	})
+}
+
+func TestMigrateToRevertsLaterScripts(t *testing.T) {
+	db, repo, q := testutils.MockAll()
+	Convey("MigrateTo Success - Scripts after the version are reverted", t, func() {
+		mockApplied(q, map[string]string{
+			"001_collection.up.js": MigrationApplied,
+			"002_data.up.js":       MigrationApplied,
+		})
+		migrator := &Migrator{Db: db, DataDir: rollbackPath, FailOnOrderMismatch: true}
+
+		ShouldBeNil(migrator.MigrateTo("001"))
+		ShouldEqual(1, db.Times("Run"))
+		ShouldEqual(1, q.Times("Update"))
+		ShouldEqual(0, repo.Times("Insert"))
+
+		err := migrator.MigrateTo("004")
+		ShouldError(err)
+		ShouldEqual("Version '004' was not found in the migration scripts.", err.Error())
+	})
+}
+
+func TestMigrateAfterRollback(t *testing.T) {
+	db, repo, q := testutils.MockAll()
+	Convey("Migrate Success - Reverted scripts are migrated again", t, func() {
+		mockApplied(q, map[string]string{
+			"001_collection.up.js": MigrationApplied,
+			"002_data.up.js":       MigrationReverted,
+		})
+		migrator := &Migrator{Db: db, DataDir: rollbackPath, FailOnOrderMismatch: true}
+
+		ShouldBeNil(migrator.Migrate())
+		ShouldEqual(2, db.Times("Run"))
+		ShouldEqual(1, q.Times("Update"))
+		ShouldEqual(1, repo.Times("Insert"))
+	})
+}
+
+func TestPlan(t *testing.T) {
+	db, repo, q := testutils.MockAll()
+	Convey("Plan reports the migration without running it", t, func() {
+		db.WhenReturn("HasRepo", true)
+		q.When("All", func(args ...interface{}) []interface{} {
+			list := args[0].(*[]*MigrationInfo)
+			*list = append(*list, &MigrationInfo{ScriptId: "script_002.js", Hash: "1234"})
+			return mock.MakeReturn(nil)
+		})
+		migrator := &Migrator{Db: db, DataDir: migrationPath, FailOnOrderMismatch: true}
+
+		plan, err := migrator.Plan()
+		ShouldBeNil(err)
+		ShouldLen(plan.Pending, 1)
+		ShouldEqual("script_001.js", plan.Pending[0].ScriptId)
+		ShouldEqual("b280f134425a4153026cf227069d4cc1", plan.Pending[0].Hash)
+		ShouldLen(plan.Applied, 0)
+		ShouldLen(plan.HashMismatches, 1)
+		ShouldEqual("1234", plan.HashMismatches[0].PreviousHash)
+		ShouldLen(plan.OrderViolations, 1)
+		ShouldEqual("script_002.js", plan.OrderViolations[0].ScriptId)
+		ShouldBeTrue(!plan.CanMigrate())
+
+		ShouldEqual(0, db.Times("CreateRepo"))
+		ShouldEqual(0, db.Times("Run"))
+		ShouldEqual(0, repo.Times("Insert"))
+		ShouldEqual(0, q.Times("Update"))
+	})
+}
+
+func TestMigrateLockNotAcquired(t *testing.T) {
+	db, repo, _ := testutils.MockAll()
+	interval := lockPollInterval
+	lockPollInterval = time.Millisecond
+	defer func() { lockPollInterval = interval }()
+
+	Convey("Migrate Failed - Lock held by another instance", t, func() {
+		repo.WhenReturn("Insert", errors.New("duplicate key"))
+		migrator := &Migrator{
+			Db:       db,
+			DataDir:  migrationPath,
+			UseLock:  true,
+			LockWait: 10 * time.Millisecond,
+		}
+
+		err := migrator.Migrate()
+		ShouldError(err)
+		ShouldEqual("Unable to acquire the migration lock within 10ms. duplicate key", err.Error())
+		ShouldEqual(1, repo.Times("AddUniqueIndex"))
+		ShouldEqual(0, db.Times("Run"))
+	})
+}
+
+func TestMigrateLockNotRenewed(t *testing.T) {
+	Convey("Migrate Failed - The lease of the lock could not be renewed", t, func() {
+		db, repo, q := testutils.MockAll()
+		q.WhenReturn("Update", errors.New("connection lost"))
+		db.When("Run", func(args ...interface{}) []interface{} {
+			time.Sleep(20 * time.Millisecond)
+			return []interface{}{nil}
+		})
+		migrator := &Migrator{
+			Db:        db,
+			DataDir:   migrationPath,
+			UseLock:   true,
+			LockLease: 3 * time.Millisecond,
+		}
+
+		err := migrator.Migrate()
+		ShouldError(err)
+		ShouldEqual("Migration aborted before running 'script_002.js', unable to renew the migration lock. connection lost", err.Error())
+		ShouldEqual(1, db.Times("Run"))
+		ShouldEqual(2, repo.Times("Insert"))
+	})
+}
+
+func TestGoMigrations(t *testing.T) {
+	Convey("Go migrations are run in order with the scripts", t, func() {
+		db, repo, _ := testutils.MockAll()
+		var order []string
+		db.When("Run", func(args ...interface{}) []interface{} {
+			order = append(order, "script")
+			return mock.MakeReturn(nil)
+		})
+		migrator := &Migrator{Db: db, DataDir: migrationPath, FailOnOrderMismatch: true}
+		migrator.Register("script_001_5_backfill.go", "v1", func(ctx context.Context, d dbx.IDatabase) error {
+			order = append(order, "go")
+			ShouldBeTrue(ctx != nil)
+			return nil
+		})
+
+		ShouldBeNil(migrator.Migrate())
+		ShouldEqual([]string{"script", "go", "script"}, order)
+		ShouldEqual(3, repo.Times("Insert"))
+	})
+
+	Convey("Migrated Go migrations are verified by version", t, func() {
+		db, _, q := testutils.MockAll()
+		q.When("All", func(args ...interface{}) []interface{} {
+			list := args[0].(*[]*MigrationInfo)
+			*list = append(*list, &MigrationInfo{
+				ScriptId: "000_seed.go",
+				Hash:     "6654c734ccab8f440ff0825eb443dc7f", // md5 of 'v1'
+			})
+			return mock.MakeReturn(nil)
+		})
+		runs := 0
+		migrator := &Migrator{Db: db, DataDir: migrationPath, FailOnOrderMismatch: true}
+		migrator.Register("000_seed.go", "v1", func(context.Context, dbx.IDatabase) error {
+			runs++
+			return nil
+		})
+
+		ShouldBeNil(migrator.Migrate())
+		ShouldEqual(0, runs)
+
+		migrator.Migrations[0].Checksum = "a1b2"
+		err := migrator.Migrate()
+		ShouldError(err)
+		ShouldEqual("File '000_seed.go' was previously migrated but hashes don't match.", err.Error())
+
+		migrator.Migrations[0].Checksum = ""
+		migrator.Migrations[0].Version = "v2"
+		err = migrator.Migrate()
+		ShouldError(err)
+		ShouldEqual("File '000_seed.go' was previously migrated but hashes don't match.", err.Error())
+	})
+}
+
+func TestMigrateFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/001_users.sql":       {Data: []byte("CREATE TABLE users (id INT)")},
+		"migrations/README.md":           {Data: []byte("docs")},
+		"migrations/002/001_roles.sql":   {Data: []byte("CREATE TABLE roles (id INT)")},
+		"migrations/003_users_index.sql": {Data: []byte("CREATE INDEX idx ON users (id)")},
+		"migrations/004_orphan.down.sql": {Data: []byte("orphan")},
+	}
+
+	Convey("Migrate Success - Scripts in a file system", t, func() {
+		db, repo, _ := testutils.MockAll()
+		var scripts []string
+		db.When("Run", func(args ...interface{}) []interface{} {
+			scripts = append(scripts, args[0].(string))
+			return mock.MakeReturn(nil)
+		})
+		migrator := &Migrator{Db: db, FS: fsys, DataDir: "migrations"}
+
+		ShouldBeNil(migrator.Migrate())
+		ShouldEqual([]string{"CREATE TABLE users (id INT)", "CREATE INDEX idx ON users (id)"}, scripts)
+		ShouldEqual(2, repo.Times("Insert"))
+	})
+
+	Convey("Migrate Success - Subdirectories are migrated if recursive", t, func() {
+		db, repo, _ := testutils.MockAll()
+		var ids []string
+		repo.When("Insert", func(args ...interface{}) []interface{} {
+			ids = append(ids, args[0].(MigrationInfo).ScriptId)
+			return mock.MakeReturn(nil)
+		})
+		migrator := &Migrator{Db: db, FS: fsys, DataDir: "migrations", Recursive: true}
+
+		ShouldBeNil(migrator.Migrate())
+		ShouldEqual([]string{"001_users.sql", "002/001_roles.sql", "003_users_index.sql"}, ids)
+	})
+}
+
+func TestStatus(t *testing.T) {
+	db, repo, q := testutils.MockAll()
+	Convey("Status reports every known script", t, func() {
+		db.WhenReturn("HasRepo", true)
+		q.When("All", func(args ...interface{}) []interface{} {
+			list := args[0].(*[]*MigrationInfo)
+			*list = append(*list,
+				&MigrationInfo{ScriptId: "000_removed.js", Hash: "abcd", Status: MigrationApplied},
+				&MigrationInfo{ScriptId: "script_001.js", Hash: "1234", Duration: 1500 * time.Millisecond},
+			)
+			return mock.MakeReturn(nil)
+		})
+		migrator := &Migrator{Db: db, DataDir: migrationPath}
+
+		statuses, err := migrator.Status()
+		ShouldBeNil(err)
+		ShouldLen(statuses, 3)
+		ShouldEqual("000_removed.js", statuses[0].ScriptId)
+		ShouldEqual(StateMissing, statuses[0].State)
+		ShouldEqual(StateModified, statuses[1].State)
+		ShouldEqual("1234", statuses[1].AppliedHash)
+		ShouldEqual("b280f134425a4153026cf227069d4cc1", statuses[1].Hash)
+		ShouldEqual(1500*time.Millisecond, statuses[1].Duration)
+		ShouldEqual(StatePending, statuses[2].State)
+		ShouldBeTrue(statuses[2].AppliedAt == nil)
+		ShouldEqual(0, db.Times("CreateRepo"))
+		ShouldEqual(0, repo.Times("Insert"))
+
+		var out bytes.Buffer
+		ShouldBeNil(FormatStatus(&out, statuses))
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		ShouldLen(lines, 4)
+		ShouldBeTrue(strings.HasPrefix(lines[0], "SCRIPT"))
+		ShouldBeTrue(strings.Contains(lines[2], "modified"))
+		ShouldBeTrue(strings.Contains(lines[2], "1.5s"))
+		ShouldEqual("script_002.js  pending  -", strings.Join(strings.Fields(lines[3])[:3], "  "))
+	})
+}
+
+func TestRepair(t *testing.T) {
+	db, repo, q := testutils.MockAll()
+	Convey("Repair updates the modified hashes only when confirmed, recording an audit", t, func() {
+		db.WhenReturn("HasRepo", true)
+		q.When("All", func(args ...interface{}) []interface{} {
+			list := args[0].(*[]*MigrationInfo)
+			*list = append(*list, &MigrationInfo{ScriptId: "script_001.js", Hash: "1234", Status: MigrationApplied})
+			return mock.MakeReturn(nil)
+		})
+		var audits []MigrationAudit
+		repo.When("Insert", func(args ...interface{}) []interface{} {
+			if audit, ok := args[0].(MigrationAudit); ok {
+				audits = append(audits, audit)
+			}
+			return mock.MakeReturn(nil)
+		})
+		migrator := &Migrator{Db: db, DataDir: migrationPath}
+
+		modified, err := migrator.Repair(false)
+		ShouldBeNil(err)
+		ShouldEqual([]string{"script_001.js"}, modified)
+		ShouldEqual(0, q.Times("Update"))
+		ShouldEqual(0, repo.Times("Insert"))
+
+		_, err = migrator.Repair(false, "script_002.js")
+		ShouldError(err)
+
+		repaired, err := migrator.Repair(true)
+		ShouldBeNil(err)
+		ShouldEqual([]string{"script_001.js"}, repaired)
+		ShouldEqual(1, q.Times("Update"))
+		ShouldLen(audits, 1)
+		ShouldEqual(AuditRepair, audits[0].Action)
+		ShouldEqual("1234", audits[0].OldHash)
+		ShouldEqual("b280f134425a4153026cf227069d4cc1", audits[0].NewHash)
+	})
+	Convey("Repair reports why the migration info could not be saved", t, func() {
+		q.WhenReturn("Update", errors.New("connection lost"))
+		migrator := &Migrator{Db: db, DataDir: migrationPath}
+
+		_, err := migrator.Repair(true)
+		ShouldError(err)
+		ShouldEqual("Unable to save migration info for 'script_001.js'. connection lost", err.Error())
+	})
+}
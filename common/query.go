@@ -1,12 +1,15 @@
 package common
 
 import (
+	"context"
+
 	"github.com/jucardi/go-db"
 )
 
 // AbstractQuery is a base query helper used for final implementations.
 type AbstractQuery struct {
 	Q          dbx.IQuery
+	Ctx        context.Context
 	LimitVal   *int
 	SkipVal    *int
 	SortFields []string
@@ -24,6 +27,11 @@ func newCond(query interface{}, args []interface{}, negated bool) *ConditionData
 	return &ConditionData{Query: query, Args: args, Negation: negated}
 }
 
+func (a *AbstractQuery) WithContext(ctx context.Context) dbx.IQuery {
+	a.Ctx = ctx
+	return a.Q
+}
+
 func (a *AbstractQuery) Limit(n int) dbx.IQuery {
 	a.LimitVal = &n
 	return a.Q
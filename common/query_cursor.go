@@ -0,0 +1,199 @@
+package common
+
+import (
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/pages"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// FieldValueFunc obtains the value of a field from an item of a query result, by the name of the field in the
+// database. Used by cursor pagination to obtain the sort key values of the items.
+type FieldValueFunc func(item interface{}, field string) (interface{}, error)
+
+func init() {
+	gob.Register(bson.ObjectId(""))
+}
+
+// WrapCursor attempts to obtain the items in the requested subset using keyset pagination and wraps the result in
+// *pages.Paginated, including the cursors to request the next and previous subsets.
+func (q *AbstractQuery) WrapCursor(result interface{}, cursor *pages.Cursor) (*pages.Paginated, error) {
+	return WrapCursorHandler(q.Q, result, cursor, "_id", BsonFieldValue)
+}
+
+// WrapCursorHandler implements cursor pagination for any query. The sort fields of the cursor are applied to the query,
+// so the query should not be sorted beforehand.
+//
+//    {q}           - The query to paginate.
+//    {result}      - A pointer to the slice where the items are stored.
+//    {cursor}      - The requested subset.
+//    {uniqueField} - A field with unique values, appended to the sort fields if not present so the order is
+//                    deterministic, e.g. the primary key. Ignored if empty.
+//    {value}       - Obtains the sort key values from the items of the result.
+func WrapCursorHandler(q dbx.IQuery, result interface{}, cursor *pages.Cursor, uniqueField string, value FieldValueFunc) (*pages.Paginated, error) {
+	resultv := reflect.ValueOf(result)
+	if resultv.Kind() != reflect.Ptr || resultv.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("unable to create Paginated, 'result' arg must be a pointer to a Slice, %v", resultv.Kind())
+	}
+	if cursor == nil || cursor.Size <= 0 {
+		return nil, fmt.Errorf("the cursor page size must be greater than zero")
+	}
+
+	sort := cursorSort(cursor.Sort, uniqueField)
+
+	var pos *pages.CursorPosition
+	if cursor.Token != "" {
+		var err error
+		if pos, err = pages.DecodeCursor(cursor.Token); err != nil {
+			return nil, err
+		}
+		if len(pos.Values) != len(sort) || strings.Join(pos.Sort, ",") != strings.Join(sort, ",") {
+			return nil, pages.ErrInvalidCursor
+		}
+	}
+
+	n, exact, err := countItems(q, cursor.Count)
+	if err != nil {
+		return nil, err
+	}
+
+	backward := pos != nil && pos.Backward
+	if pos != nil {
+		q.Where(keysetFilter(sort, pos.Values, backward))
+	}
+
+	fields := sort
+	if backward {
+		fields = make([]string, len(sort))
+		for i, f := range sort {
+			fields[i] = invertSort(f)
+		}
+	}
+
+	if err := q.Sort(fields...).Limit(cursor.Size + 1).All(result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal page, %v", err)
+	}
+
+	items := resultv.Elem()
+	more := trim(result, cursor.Size)
+	if backward {
+		reverse(items)
+	}
+
+	ret, err := pages.CreatePaginated(&pages.Page{Size: cursor.Size, Sort: cursor.Sort}, result, n)
+	if err != nil {
+		return nil, err
+	}
+	if !exact {
+		ret.CountMode = cursor.Count
+	}
+	if items.Len() == 0 {
+		return ret, nil
+	}
+
+	// Going forward, there is a previous subset if a cursor was provided. Going backward, there is always a next one.
+	hasNext, hasPrev := more, pos != nil
+	if backward {
+		hasNext, hasPrev = true, more
+	}
+
+	if hasNext {
+		if ret.NextCursor, err = encodeCursor(items.Index(items.Len()-1).Interface(), sort, false, value); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		if ret.PrevCursor, err = encodeCursor(items.Index(0).Interface(), sort, true, value); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// BsonFieldValue obtains the value of a field from an item by marshaling it to BSON. Nested fields are separated by a
+// dot.
+func BsonFieldValue(item interface{}, field string) (interface{}, error) {
+	data, err := bson.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	doc := bson.M{}
+	if err := bson.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+
+	var ret interface{} = doc
+	for _, key := range strings.Split(field, ".") {
+		m, ok := ret.(bson.M)
+		if !ok {
+			return nil, nil
+		}
+		ret = m[key]
+	}
+	return ret, nil
+}
+
+func encodeCursor(item interface{}, sort []string, backward bool, value FieldValueFunc) (string, error) {
+	values := make([]interface{}, len(sort))
+	for i, f := range sort {
+		v, err := value(item, strings.TrimPrefix(f, "-"))
+		if err != nil {
+			return "", fmt.Errorf("unable to obtain the value of '%s' for the cursor, %v", f, err)
+		}
+		values[i] = v
+	}
+	return pages.EncodeCursor(&pages.CursorPosition{Values: values, Sort: sort, Backward: backward})
+}
+
+// cursorSort returns the sort fields with the unique field appended, unless already present.
+func cursorSort(sort []string, uniqueField string) []string {
+	ret := append([]string{}, sort...)
+	if uniqueField == "" {
+		return ret
+	}
+	for _, f := range sort {
+		if strings.TrimPrefix(f, "-") == uniqueField {
+			return ret
+		}
+	}
+	return append(ret, uniqueField)
+}
+
+// keysetFilter builds the filter that matches the items after the provided sort key values, or before them if
+// backward is true. For the sort (a, -b) it is: a > x OR (a = x AND b < y).
+func keysetFilter(sort []string, values []interface{}, backward bool) *dbx.Filter {
+	var or []*dbx.Filter
+	for i, f := range sort {
+		var and []*dbx.Filter
+		for j := 0; j < i; j++ {
+			and = append(and, dbx.Eq(strings.TrimPrefix(sort[j], "-"), values[j]))
+		}
+
+		field := strings.TrimPrefix(f, "-")
+		if strings.HasPrefix(f, "-") != backward {
+			and = append(and, dbx.Lt(field, values[i]))
+		} else {
+			and = append(and, dbx.Gt(field, values[i]))
+		}
+		or = append(or, dbx.And(and...))
+	}
+	return dbx.Or(or...)
+}
+
+func invertSort(field string) string {
+	if strings.HasPrefix(field, "-") {
+		return field[1:]
+	}
+	return "-" + field
+}
+
+func reverse(items reflect.Value) {
+	swap := reflect.Swapper(items.Interface())
+	for i, j := 0, items.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
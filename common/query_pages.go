@@ -2,6 +2,8 @@ package common
 
 import (
 	"fmt"
+	"reflect"
+
 	"github.com/jucardi/go-db"
 	"github.com/jucardi/go-db/pages"
 )
@@ -34,16 +36,62 @@ func WrapPageHandler(q dbx.IQuery, result interface{}, page ...*pages.Page) (*pa
 		return wrap(q, result, nil)
 	}
 
-	n, err := q.Count()
-
+	p := page[0]
+	n, exact, err := countItems(q, p.Count)
 	if err != nil {
-		return nil, fmt.Errorf("unable to obtain a count of elements, %v", err)
+		return nil, err
 	}
 
-	p := page[0]
 	q.Page(page...)
+	if exact {
+		return wrap(q, result, p, n)
+	}
+
+	// Without an exact count, whether there is a next page is determined by fetching one more item.
+	if err := q.Limit(p.Size + 1).All(result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal page, %v", err)
+	}
+	more := trim(result, p.Size)
 
-	return wrap(q, result, p, n)
+	ret, err := pages.CreatePaginated(p, result, n)
+	if err != nil {
+		return nil, err
+	}
+	ret.CountMode = p.Count
+	ret.HasNext = more
+	return ret, nil
+}
+
+// countItems obtains the total amount of items of the query according to the count mode. Returns whether the count is
+// exact.
+func countItems(q dbx.IQuery, mode pages.CountMode) (int, bool, error) {
+	switch mode {
+	case pages.CountNone:
+		return 0, false, nil
+	case pages.CountEstimated:
+		if e, ok := q.(dbx.IQueryEstimatedCount); ok {
+			// Falls back to the exact count if the estimation fails.
+			if n, ok, err := e.EstimatedCount(); err == nil && ok {
+				return n, false, nil
+			}
+		}
+	}
+
+	n, err := q.Count()
+	if err != nil {
+		return 0, false, fmt.Errorf("unable to obtain a count of elements, %v", err)
+	}
+	return n, true, nil
+}
+
+// trim reduces the slice pointed by result to the provided size. Returns true if it was longer.
+func trim(result interface{}, size int) bool {
+	items := reflect.ValueOf(result).Elem()
+	if items.Len() <= size {
+		return false
+	}
+	items.Set(items.Slice(0, size))
+	return true
 }
 
 func wrap(q dbx.IQuery, result interface{}, p *pages.Page, n ...int) (*pages.Paginated, error) {
@@ -53,3 +101,40 @@ func wrap(q dbx.IQuery, result interface{}, p *pages.Page, n ...int) (*pages.Pag
 
 	return pages.CreatePaginated(p, result, n...)
 }
+
+// HasConditions indicates whether the query has any condition which filters the records. Empty conditions, such as a
+// nil condition, an empty map or an empty `dbx.And`, are ignored.
+func (a *AbstractQuery) HasConditions() bool {
+	for _, block := range a.Queries {
+		for _, cond := range block {
+			if !emptyCondition(cond.Query) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func emptyCondition(query interface{}) bool {
+	switch q := query.(type) {
+	case nil:
+		return true
+	case *dbx.Filter:
+		if q == nil {
+			return true
+		}
+		if q.Op != dbx.FilterAnd {
+			return false
+		}
+		for _, f := range q.Filters {
+			if !emptyCondition(f) {
+				return false
+			}
+		}
+		return true
+	case string:
+		return q == ""
+	}
+	v := reflect.ValueOf(query)
+	return v.Kind() == reflect.Map && v.Len() == 0
+}
@@ -0,0 +1 @@
+db.createCollection('rollback_collection');
@@ -0,0 +1 @@
+db.getCollection('rollback_collection').deleteMany({ "a": { "$in": ["abc", "def"] } });
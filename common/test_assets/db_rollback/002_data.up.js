@@ -0,0 +1,4 @@
+db.getCollection('rollback_collection').insertMany([
+    { "a": "abc", "c": 123 },
+    { "a": "def", "c": 456 }
+]);
@@ -0,0 +1 @@
+db.getCollection('rollback_collection').createIndex({ "a": 1 });
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/logger"
+)
+
+// TransactionHandler begins a transaction in the provided database and runs the given function with it. The transaction
+// is committed if the function succeeds, or rolled back if the function returns an error or panics. Panics are
+// propagated after the rollback. If the database does not support rollbacks, the error of the function is wrapped in an
+// error reporting that the changes were not reverted.
+func TransactionHandler(db dbx.ITransactional, fn func(tx dbx.IDatabase) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction, %v", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			rollback(tx)
+			panic(r)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rollback(tx) == dbx.ErrRollbackNotSupported {
+			return fmt.Errorf("%w. The transaction was not rolled back, %s", err, dbx.ErrRollbackNotSupported.Error())
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func rollback(tx dbx.ITransaction) error {
+	err := tx.Rollback()
+	if err != nil && err != dbx.ErrRollbackNotSupported {
+		logger.Get().Error(fmt.Sprintf("Error occurred while rolling back transaction, %s", err.Error()))
+	}
+	return err
+}
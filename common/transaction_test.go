@@ -0,0 +1,75 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/testutils"
+	. "github.com/jucardi/go-testx/testx"
+)
+
+func TestTransactionCommit(t *testing.T) {
+	db := testutils.MockDB()
+	db.WhenReturn("Begin", db, nil)
+
+	Convey("Transaction committed when the function succeeds", t, func() {
+		err := TransactionHandler(db, func(tx dbx.IDatabase) error {
+			return nil
+		})
+		ShouldBeNil(err)
+		ShouldEqual(1, db.Times("Begin"))
+		ShouldEqual(1, db.Times("Commit"))
+		ShouldEqual(0, db.Times("Rollback"))
+	})
+}
+
+func TestTransactionRollbackOnError(t *testing.T) {
+	db := testutils.MockDB()
+	db.WhenReturn("Begin", db, nil)
+
+	Convey("Transaction rolled back when the function fails", t, func() {
+		err := TransactionHandler(db, func(tx dbx.IDatabase) error {
+			return errors.New("some error")
+		})
+		ShouldError(err)
+		ShouldEqual("some error", err.Error())
+		ShouldEqual(0, db.Times("Commit"))
+		ShouldEqual(1, db.Times("Rollback"))
+	})
+}
+
+func TestTransactionRollbackNotSupported(t *testing.T) {
+	db := testutils.MockDB()
+	db.WhenReturn("Begin", db, nil)
+	db.WhenReturn("Rollback", dbx.ErrRollbackNotSupported)
+
+	Convey("The error reports that the changes were not reverted", t, func() {
+		fnErr := errors.New("some error")
+		err := TransactionHandler(db, func(tx dbx.IDatabase) error {
+			return fnErr
+		})
+		ShouldError(err)
+		ShouldBeTrue(errors.Is(err, fnErr))
+		ShouldEqual("some error. The transaction was not rolled back, "+dbx.ErrRollbackNotSupported.Error(), err.Error())
+		ShouldEqual(1, db.Times("Rollback"))
+	})
+}
+
+func TestTransactionRollbackOnPanic(t *testing.T) {
+	db := testutils.MockDB()
+	db.WhenReturn("Begin", db, nil)
+
+	Convey("Transaction rolled back when the function panics", t, func() {
+		func() {
+			defer func() {
+				ShouldEqual("some panic", recover())
+			}()
+			_ = TransactionHandler(db, func(tx dbx.IDatabase) error {
+				panic("some panic")
+			})
+		}()
+		ShouldEqual(0, db.Times("Commit"))
+		ShouldEqual(1, db.Times("Rollback"))
+	})
+}
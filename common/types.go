@@ -4,10 +4,78 @@ import (
 	"time"
 )
 
+const (
+	// MigrationApplied is the status of a migration script that has been applied
+	MigrationApplied = "applied"
+
+	// MigrationReverted is the status of a migration script that has been reverted by its down script
+	MigrationReverted = "reverted"
+
+	// MigrationFailed is the status of a migration script that failed to run
+	MigrationFailed = "failed"
+
+	// AuditRepair is the action of the audit records of repaired scripts
+	AuditRepair = "repair"
+
+	// AuditBaseline is the action of the audit records of scripts recorded by a baseline
+	AuditBaseline = "baseline"
+)
+
 type MigrationInfo struct {
 	//IdInt     uint      `gorm:"primary_key"`
 	//IdString  string    `bson:"_id"`
 	ScriptId  string    `bson:"script_id"`
 	Hash      string    `bson:"hash"`
 	Timestamp time.Time `bson:"timestamp"`
+
+	// Status is the status of the script, 'applied', 'reverted' or 'failed'. Records created by previous versions do not
+	// have a status, and are considered applied.
+	Status string `bson:"status"`
+
+	// RevertedAt is the time when the script was reverted, if it is reverted
+	RevertedAt *time.Time `bson:"reverted_at,omitempty"`
+
+	// Error is the error of the last attempt to run the script, if it failed
+	Error string `bson:"error,omitempty" gorm:"type:text"`
+
+	// Duration is the time it took to run the script
+	Duration time.Duration `bson:"duration"`
+}
+
+// IsApplied indicates whether the script is currently applied
+func (m *MigrationInfo) IsApplied() bool {
+	return m.Status == "" || m.Status == MigrationApplied
+}
+
+// MigrationLock is the record that grants an instance the exclusive right to run a migration. It is stored in the
+// migration lock repository ('_migration_lock' by default) while a migration is running.
+type MigrationLock struct {
+	LockId string `bson:"lock_id" gorm:"primary_key"`
+
+	// Owner identifies the instance holding the lock
+	Owner string `bson:"owner"`
+
+	// ExpiresAt is the time when the lock is considered abandoned and may be taken by another instance. It is renewed
+	// periodically while the migration is running.
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// MigrationAudit is a record of a manual operation on the migration records, stored in the migration audit repository
+// ('_migration_audit' by default).
+type MigrationAudit struct {
+	// Action is the operation, 'repair' or 'baseline'
+	Action string `bson:"action"`
+
+	ScriptId string `bson:"script_id"`
+
+	// OldHash is the hash recorded before the operation. Empty if the script was not recorded.
+	OldHash string `bson:"old_hash"`
+
+	// NewHash is the hash recorded by the operation
+	NewHash string `bson:"new_hash"`
+
+	// Operator identifies the user and host that performed the operation
+	Operator string `bson:"operator"`
+
+	Timestamp time.Time `bson:"timestamp"`
 }
@@ -1,17 +1,27 @@
 package dbx
 
 import (
+	"context"
+	"io/fs"
+
 	"github.com/jucardi/go-logger-lib/log"
 )
 
 // IDatabase ...
 type IDatabase interface {
+	// Set of functions to work with transactions, defined in the following interface(s):
+	ITransactional
+
 	// Clone clones a new db connection without search conditions
 	Clone() IDatabase
 
 	// Close close current db connection.
 	Close()
 
+	// WithContext returns a copy of the database bound to the provided context. Operations executed through the returned
+	// database, or the repositories and queries obtained from it, are aborted when the context is done.
+	WithContext(ctx context.Context) IDatabase
+
 	// Callbacks returns the callbacks container to be able to add callbacks on Create, Update, Delete or Query.
 	Callbacks() ICallbacksManager
 
@@ -37,9 +47,20 @@ type IDatabase interface {
 	// Uses the reference object to create the schema (SQL).
 	CreateRepo(name string, ref ...interface{}) error
 
-	// Migrate starts a migration process using the scripts located in the 'dataDir'
+	// Migrate starts a migration process using the scripts located in the 'dataDir'. If the database is bound to a
+	// context, the migration is aborted between scripts when the context is done.
 	Migrate(dataDir string, failOnOrderMismatch ...bool) error
 
+	// MigrateFS starts a migration process using the scripts located in the 'dir' of the provided file system, e.g. an
+	// `embed.FS` to migrate scripts compiled in the binary. Use '.' for the root of the file system.
+	MigrateFS(fsys fs.FS, dir string, failOnOrderMismatch ...bool) error
+
 	// SetScriptExecutor sets a custom script executor to be used when running Exec
 	SetScriptExecutor(executor ScriptExecutor)
 }
+
+// IRepoSchemaUpdater is implemented by the databases of providers with a schema (SQL), to update the schema of an
+// existing repository using the reference objects, adding the missing columns.
+type IRepoSchemaUpdater interface {
+	UpdateRepo(name string, ref ...interface{}) error
+}
@@ -17,8 +17,17 @@ const (
 	MethodAfterFound  = "AfterFound"
 )
 
-var errType = reflect.TypeOf((*error)(nil))
+var errType = reflect.TypeOf((*error)(nil)).Elem()
 
+// Invoke invokes the lifecycle hook method, e.g. `MethodAfterFound`, on each of the provided entities that define it.
+// Hooks must have the signature `func() error`. Providers invoke the hooks as follows:
+//
+//	BeforeCreate, AfterCreate  - on the inserted records. For SQL they are invoked by gorm.
+//	BeforeUpdate, AfterUpdate  - on the update document.
+//	BeforeDelete, AfterDelete  - on the deleted records, SQL only, when the model of the query is known.
+//	AfterFound                 - on the records returned by First, One, Last, All and iterators.
+//
+// An error returned by a hook aborts the operation, and is returned by it.
 func Invoke(method string, entity ...interface{}) error {
 	for _, e := range entity {
 		val := reflect.ValueOf(e)
@@ -49,3 +58,48 @@ func Invoke(method string, entity ...interface{}) error {
 	}
 	return nil
 }
+
+// InvokeResult invokes the method on the result of a query. If the result is a pointer to a slice or array, the method
+// is invoked on each of its elements, otherwise on the result itself. Nothing is done if the type of the result, or of
+// its elements, does not define the method.
+func InvokeResult(method string, result interface{}) error {
+	if IsNil(result) {
+		return nil
+	}
+
+	val := reflect.ValueOf(result)
+	items := reflect.Indirect(val)
+	if items.Kind() != reflect.Slice && items.Kind() != reflect.Array {
+		if !hasMethod(val.Type(), method) {
+			return nil
+		}
+		return Invoke(method, result)
+	}
+
+	elem := items.Type().Elem()
+	if !hasMethod(elem, method) {
+		return nil
+	}
+	for i := 0; i < items.Len(); i++ {
+		item := items.Index(i)
+		if item.Kind() != reflect.Ptr && item.CanAddr() {
+			item = item.Addr()
+		}
+		if err := Invoke(method, item.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasMethod indicates whether the type, or a pointer to it, defines the method
+func hasMethod(t reflect.Type, method string) bool {
+	if _, ok := t.MethodByName(method); ok {
+		return true
+	}
+	if t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface {
+		_, ok := reflect.PtrTo(t).MethodByName(method)
+		return ok
+	}
+	return false
+}
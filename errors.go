@@ -32,3 +32,17 @@ func (err *DbError) Is(t ErrType) bool {
 func (err *DbError) IsNotFound() bool {
 	return err.Code == ErrNotFound
 }
+
+// ErrNotInTransaction is returned when attempting to commit or rollback a database instance that was not obtained by
+// `Begin`
+var ErrNotInTransaction = &DbError{
+	Code:    ErrDbOperation,
+	Message: "the database instance is not in a transaction",
+}
+
+// ErrRollbackNotSupported is returned by `Rollback` when the transaction was ended but the changes made in it could not
+// be reverted, since the database only supports best-effort transactions (MongoDB).
+var ErrRollbackNotSupported = &DbError{
+	Code:    ErrDbOperation,
+	Message: "rollback is not supported by the database, the changes made in the transaction were not reverted",
+}
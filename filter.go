@@ -0,0 +1,149 @@
+package dbx
+
+import "reflect"
+
+// FilterOp is the operator of a Filter
+type FilterOp string
+
+const (
+	FilterEq     FilterOp = "eq"
+	FilterNe     FilterOp = "ne"
+	FilterIn     FilterOp = "in"
+	FilterNin    FilterOp = "nin"
+	FilterGt     FilterOp = "gt"
+	FilterGte    FilterOp = "gte"
+	FilterLt     FilterOp = "lt"
+	FilterLte    FilterOp = "lte"
+	FilterLike   FilterOp = "like"
+	FilterRegex  FilterOp = "regex"
+	FilterExists FilterOp = "exists"
+	FilterAnd    FilterOp = "and"
+	FilterOr     FilterOp = "or"
+	FilterNot    FilterOp = "not"
+)
+
+// Filter is a backend-neutral condition which can be used with `Where` and `Not` on any provider. Filters should be
+// created using the builder functions in this package, for example:
+//
+//	db.R("users").Where(dbx.And(
+//		dbx.Eq("status", "active"),
+//		dbx.Or(dbx.Gte("age", 18), dbx.Exists("guardian")),
+//	)).All(&users)
+//
+// Field names are the names used in the database (columns for SQL, keys for MongoDB). Nested fields are separated by a
+// dot, e.g. "address.city", which translates to a nested key for MongoDB and to a qualified column for SQL.
+type Filter struct {
+	// Op is the operator of the filter
+	Op FilterOp
+
+	// Field is the field the filter applies to. Not used by And, Or and Not.
+	Field string
+
+	// Value is the value to compare against. For In and Nin it is a []interface{}, for Exists it is a bool.
+	Value interface{}
+
+	// Filters are the inner filters of And, Or and Not.
+	Filters []*Filter
+}
+
+// Eq matches the records where the field is equal to the provided value. A nil value matches null or missing fields.
+func Eq(field string, value interface{}) *Filter {
+	return &Filter{Op: FilterEq, Field: field, Value: value}
+}
+
+// Ne matches the records where the field is not equal to the provided value, including the records where the field is
+// null or missing. A nil value matches the records where the field is set.
+func Ne(field string, value interface{}) *Filter {
+	return &Filter{Op: FilterNe, Field: field, Value: value}
+}
+
+// In matches the records where the field is equal to any of the provided values. A single slice argument is expanded
+// into its elements.
+func In(field string, values ...interface{}) *Filter {
+	return &Filter{Op: FilterIn, Field: field, Value: flatten(values)}
+}
+
+// Nin matches the records where the field is not equal to any of the provided values. A single slice argument is
+// expanded into its elements.
+func Nin(field string, values ...interface{}) *Filter {
+	return &Filter{Op: FilterNin, Field: field, Value: flatten(values)}
+}
+
+// Gt matches the records where the field is greater than the provided value.
+func Gt(field string, value interface{}) *Filter {
+	return &Filter{Op: FilterGt, Field: field, Value: value}
+}
+
+// Gte matches the records where the field is greater than or equal to the provided value.
+func Gte(field string, value interface{}) *Filter {
+	return &Filter{Op: FilterGte, Field: field, Value: value}
+}
+
+// Lt matches the records where the field is lower than the provided value.
+func Lt(field string, value interface{}) *Filter {
+	return &Filter{Op: FilterLt, Field: field, Value: value}
+}
+
+// Lte matches the records where the field is lower than or equal to the provided value.
+func Lte(field string, value interface{}) *Filter {
+	return &Filter{Op: FilterLte, Field: field, Value: value}
+}
+
+// Like matches the records where the field matches the provided SQL LIKE pattern, where '%' matches any sequence of
+// characters and '_' matches a single character. Use '\' to escape them.
+func Like(field string, pattern string) *Filter {
+	return &Filter{Op: FilterLike, Field: field, Value: pattern}
+}
+
+// Regex matches the records where the field matches the provided regular expression. For SQL, the regular expression
+// syntax depends on the database engine.
+func Regex(field string, pattern string) *Filter {
+	return &Filter{Op: FilterRegex, Field: field, Value: pattern}
+}
+
+// Exists matches the records where the field exists and is not null. If 'exists' is false, matches the records where
+// the field is missing or null instead.
+func Exists(field string, exists ...bool) *Filter {
+	return &Filter{Op: FilterExists, Field: field, Value: len(exists) == 0 || exists[0]}
+}
+
+// And matches the records that meet all the provided filters.
+func And(filters ...*Filter) *Filter {
+	return &Filter{Op: FilterAnd, Filters: filters}
+}
+
+// Or matches the records that meet any of the provided filters.
+func Or(filters ...*Filter) *Filter {
+	return &Filter{Op: FilterOr, Filters: filters}
+}
+
+// Not matches the records that do not meet the provided filter.
+func Not(filter *Filter) *Filter {
+	return &Filter{Op: FilterNot, Filters: []*Filter{filter}}
+}
+
+// Values returns the values of an In or Nin filter.
+func (f *Filter) Values() []interface{} {
+	if ret, ok := f.Value.([]interface{}); ok {
+		return ret
+	}
+	if f.Value == nil {
+		return nil
+	}
+	return []interface{}{f.Value}
+}
+
+func flatten(values []interface{}) []interface{} {
+	if len(values) != 1 || values[0] == nil {
+		return values
+	}
+	v := reflect.ValueOf(values[0])
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array || v.Type().Elem().Kind() == reflect.Uint8 {
+		return values
+	}
+	ret := make([]interface{}, v.Len())
+	for i := range ret {
+		ret[i] = v.Index(i).Interface()
+	}
+	return ret
+}
@@ -1,14 +1,20 @@
 module github.com/jucardi/go-db
 
-go 1.16
+go 1.18
 
 require (
 	github.com/jinzhu/gorm v1.9.16
 	github.com/jucardi/go-beans v1.1.2
 	github.com/jucardi/go-logger-lib v1.0.5
-	github.com/jucardi/go-osx v1.0.1
 	github.com/jucardi/go-streams v1.0.3
 	github.com/jucardi/go-strings v1.0.4
 	github.com/jucardi/go-testx v1.0.9
 	gopkg.in/mgo.v2 v2.0.0-20190816093944-a6b53ec6cb22
+	gopkg.in/yaml.v2 v2.4.0
+)
+
+require (
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/lib/pq v1.1.1 // indirect
+	github.com/mattn/go-sqlite3 v1.14.0 // indirect
 )
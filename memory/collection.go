@@ -0,0 +1,144 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/entity"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type collection struct {
+	db   *database
+	name string
+	ctx  context.Context
+}
+
+func newCollection(db *database, name string) *collection {
+	return &collection{
+		db:   db,
+		name: name,
+		ctx:  db.ctx,
+	}
+}
+
+func (c *collection) WithContext(ctx context.Context) dbx.IRepository {
+	ret := *c
+	ret.ctx = ctx
+	return &ret
+}
+
+// Insert inserts one or more records in the repository. The records are stored as BSON documents, and an ObjectId is
+// generated as '_id' for those records that do not have one.
+func (c *collection) Insert(docs ...interface{}) error {
+	if err := entity.Invoke(entity.MethodBeforeCreate, docs...); err != nil {
+		return err
+	}
+	scope := &dbx.Scope{
+		Context:   c.ctx,
+		Operation: dbx.OpCreate,
+		Repo:      c.name,
+		Documents: docs,
+	}
+	return c.db.callbacks.Run(scope, func() error {
+		if err := c.insert(docs); err != nil {
+			return err
+		}
+		return entity.Invoke(entity.MethodAfterCreate, docs...)
+	})
+}
+
+func (c *collection) insert(docs []interface{}) error {
+	values := make([]bson.M, len(docs))
+	for i, d := range docs {
+		doc, err := toDoc(d)
+		if err != nil {
+			return &dbx.DbError{
+				Message: fmt.Sprintf("unable to convert the record into a document, %s", err.Error()),
+				Code:    dbx.ErrDbOperation,
+			}
+		}
+		assignId(doc, d)
+		values[i] = doc
+	}
+
+	s := c.db.store
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	r := s.repo(c.name, true)
+	all := append(append([]bson.M{}, r.docs...), values...)
+	if err := r.checkUnique(all); err != nil {
+		return err
+	}
+	r.docs = all
+	return nil
+}
+
+func (c *collection) Drop() error {
+	s := c.db.store
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	delete(s.repos, c.name)
+	s.touch(c.name)
+	return nil
+}
+
+func (c *collection) Where(condition interface{}, args ...interface{}) dbx.IQuery {
+	return newQuery(c).Where(condition, args...)
+}
+
+func (c *collection) Not(condition interface{}, args ...interface{}) dbx.IQuery {
+	return newQuery(c).Not(condition, args...)
+}
+
+func (c *collection) AddIndex(indexName string, fields ...string) error {
+	return c.addIndex(indexName, fields, false)
+}
+
+func (c *collection) DropIndex(indexName string) error {
+	s := c.db.store
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	r := s.repo(c.name, false)
+	if r == nil || r.indexes[indexName] == nil {
+		return &dbx.DbError{
+			Message: fmt.Sprintf("index '%s' not found", indexName),
+			Code:    dbx.ErrNotFound,
+		}
+	}
+	s.touch(c.name)
+	delete(r.indexes, indexName)
+	return nil
+}
+
+// AddUniqueIndex adds a unique index for the provided fields. Returns an error if the existing records violate it.
+func (c *collection) AddUniqueIndex(indexName string, fields ...string) error {
+	return c.addIndex(indexName, fields, true)
+}
+
+// Delete removes all the records that match the provided condition.
+func (c *collection) Delete(query interface{}, args ...interface{}) error {
+	q := newQuery(c)
+	q.Where(query, args...)
+	return q.remove(true)
+}
+
+func (c *collection) addIndex(name string, fields []string, unique bool) error {
+	s := c.db.store
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	r := s.repo(c.name, true)
+	idx := &index{fields: fields, unique: unique}
+	if unique {
+		check := &repo{docs: r.docs, indexes: map[string]*index{name: idx}}
+		if err := check.checkUnique(r.docs); err != nil {
+			return err
+		}
+	}
+	r.indexes[name] = idx
+	return nil
+}
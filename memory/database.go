@@ -0,0 +1,188 @@
+package memory
+
+import (
+	"context"
+	"io/fs"
+
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/common"
+	"github.com/jucardi/go-logger-lib/log"
+)
+
+var (
+	_ dbx.ITransaction = (*database)(nil)
+	_ dbx.IRepository  = (*collection)(nil)
+	_ dbx.IQuery       = (*query)(nil)
+)
+
+type database struct {
+	store     *store
+	parent    *store
+	ctx       context.Context
+	executor  dbx.ScriptExecutor
+	callbacks *common.CallbacksManager
+}
+
+func fromStore(s *store) *database {
+	return &database{
+		store:     s,
+		callbacks: common.NewCallbacksManager(),
+	}
+}
+
+func (d *database) Clone() dbx.IDatabase {
+	ret := d.derive()
+	ret.parent = nil
+	ret.executor = nil
+	return ret
+}
+
+func (d *database) Close() {
+}
+
+func (d *database) WithContext(ctx context.Context) dbx.IDatabase {
+	ret := d.derive()
+	ret.ctx = ctx
+	return ret
+}
+
+// Begin begins a transaction. The transaction works on a snapshot of the data. When committed, the repositories
+// modified by the transaction replace the ones of the database, so changes made by others to those repositories while
+// the transaction is in progress are lost. Other repositories are not affected.
+func (d *database) Begin() (dbx.ITransaction, error) {
+	if err := d.checkContext(); err != nil {
+		return nil, err
+	}
+	ret := d.derive()
+	ret.store = d.store.snapshot()
+	ret.parent = d.store
+	return ret, nil
+}
+
+func (d *database) Commit() error {
+	if d.parent == nil {
+		return dbx.ErrNotInTransaction
+	}
+	d.store.mx.RLock()
+	defer d.store.mx.RUnlock()
+	d.parent.mx.Lock()
+	defer d.parent.mx.Unlock()
+
+	d.parent.merge(d.store)
+	d.parent = nil
+	d.store = newStore()
+	return nil
+}
+
+func (d *database) Rollback() error {
+	if d.parent == nil {
+		return dbx.ErrNotInTransaction
+	}
+	d.parent = nil
+	d.store = newStore()
+	return nil
+}
+
+func (d *database) WithTransaction(fn func(tx dbx.IDatabase) error) error {
+	return common.TransactionHandler(d, fn)
+}
+
+// AtomicTransactions returns true, changes made in a transaction that is rolled back are discarded.
+func (d *database) AtomicTransactions() bool {
+	return true
+}
+
+func (d *database) Callbacks() dbx.ICallbacksManager {
+	return d.callbacks
+}
+
+func (d *database) SetLogger(log.ILogger) {
+}
+
+func (d *database) R(name string) dbx.IRepository {
+	return newCollection(d, name)
+}
+
+func (d *database) Repo(name string) dbx.IRepository {
+	return d.R(name)
+}
+
+// Exec is not supported by the in-memory database, since there is no script language to execute.
+func (d *database) Exec(string, interface{}) error {
+	return &dbx.DbError{
+		Message: "scripts are not supported by the in-memory database",
+		Code:    dbx.ErrDbOperation,
+	}
+}
+
+// Run executes the provided script using the script executor set by `SetScriptExecutor`. Scripts are not supported
+// otherwise.
+func (d *database) Run(script string) error {
+	if err := d.checkContext(); err != nil {
+		return err
+	}
+	if d.executor != nil {
+		return d.executor(script)
+	}
+	return d.Exec(script, nil)
+}
+
+func (d *database) HasRepo(name string) bool {
+	d.store.mx.RLock()
+	defer d.store.mx.RUnlock()
+	return d.store.repo(name, false) != nil
+}
+
+// CreateRepo creates an empty repository if it does not exist. The reference objects are ignored.
+func (d *database) CreateRepo(name string, _ ...interface{}) error {
+	d.store.mx.Lock()
+	defer d.store.mx.Unlock()
+	d.store.repo(name, true)
+	return nil
+}
+
+func (d *database) Migrate(dataDir string, failOnOrderMismatch ...bool) error {
+	fail := true
+	if len(failOnOrderMismatch) > 0 {
+		fail = failOnOrderMismatch[0]
+	}
+	migrator := &common.Migrator{
+		Db:                  d,
+		DataDir:             dataDir,
+		FailOnOrderMismatch: fail,
+		Context:             d.ctx,
+	}
+	return migrator.Migrate()
+}
+
+func (d *database) MigrateFS(fsys fs.FS, dir string, failOnOrderMismatch ...bool) error {
+	fail := true
+	if len(failOnOrderMismatch) > 0 {
+		fail = failOnOrderMismatch[0]
+	}
+	migrator := &common.Migrator{
+		Db:                  d,
+		FS:                  fsys,
+		DataDir:             dir,
+		FailOnOrderMismatch: fail,
+		Context:             d.ctx,
+	}
+	return migrator.Migrate()
+}
+
+func (d *database) SetScriptExecutor(executor dbx.ScriptExecutor) {
+	d.executor = executor
+}
+
+// derive returns a shallow copy of the database
+func (d *database) derive() *database {
+	ret := *d
+	return &ret
+}
+
+func (d *database) checkContext() error {
+	if d.ctx != nil {
+		return d.ctx.Err()
+	}
+	return nil
+}
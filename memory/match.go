@@ -0,0 +1,494 @@
+package memory
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/common"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// toFilter translates a query condition into a *dbx.Filter. Supported conditions are *dbx.Filter, maps and structs.
+// Map keys are matched by equality, unless the value is a map of MongoDB operators (e.g. bson.M{"$gt": 5}) or the key is
+// one of the $and, $or or $nor logical operators. The non-zero fields of structs are matched by equality.
+func toFilter(condition interface{}) (*dbx.Filter, error) {
+	if f, ok := condition.(*dbx.Filter); ok {
+		return f, nil
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(condition))
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() == reflect.String {
+			return mapToFilter(v)
+		}
+	case reflect.Struct:
+		var filters []*dbx.Filter
+		for key, value := range structFields(v) {
+			filters = append(filters, dbx.Eq(key, value))
+		}
+		return dbx.And(filters...), nil
+	}
+
+	return nil, unsupported("query condition", condition)
+}
+
+func mapToFilter(v reflect.Value) (*dbx.Filter, error) {
+	keys := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+
+	var filters []*dbx.Filter
+	for _, k := range keys {
+		value := v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())).Interface()
+		f, err := keyToFilter(k, value)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return dbx.And(filters...), nil
+}
+
+func keyToFilter(key string, value interface{}) (*dbx.Filter, error) {
+	switch key {
+	case "$and", "$or", "$nor":
+		inner, err := toFilters(value)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "$and":
+			return dbx.And(inner...), nil
+		case "$or":
+			return dbx.Or(inner...), nil
+		default:
+			return dbx.Not(dbx.Or(inner...)), nil
+		}
+	}
+
+	ops := reflect.ValueOf(value)
+	if ops.Kind() != reflect.Map || ops.Type().Key().Kind() != reflect.String || ops.Len() == 0 {
+		return dbx.Eq(key, value), nil
+	}
+	for _, k := range ops.MapKeys() {
+		if !strings.HasPrefix(k.String(), "$") {
+			return dbx.Eq(key, value), nil
+		}
+	}
+
+	var filters []*dbx.Filter
+	for _, k := range ops.MapKeys() {
+		arg := ops.MapIndex(k).Interface()
+		switch k.String() {
+		case "$eq":
+			filters = append(filters, dbx.Eq(key, arg))
+		case "$ne":
+			filters = append(filters, dbx.Ne(key, arg))
+		case "$gt":
+			filters = append(filters, dbx.Gt(key, arg))
+		case "$gte":
+			filters = append(filters, dbx.Gte(key, arg))
+		case "$lt":
+			filters = append(filters, dbx.Lt(key, arg))
+		case "$lte":
+			filters = append(filters, dbx.Lte(key, arg))
+		case "$in":
+			filters = append(filters, dbx.In(key, arg))
+		case "$nin":
+			filters = append(filters, dbx.Nin(key, arg))
+		case "$exists":
+			exists, _ := arg.(bool)
+			filters = append(filters, dbx.Exists(key, exists))
+		case "$regex":
+			filters = append(filters, dbx.Regex(key, fmt.Sprint(arg)))
+		default:
+			return nil, &dbx.DbError{
+				Message: fmt.Sprintf("unsupported query operator '%s'", k.String()),
+				Code:    dbx.ErrDbOperation,
+			}
+		}
+	}
+	return dbx.And(filters...), nil
+}
+
+func toFilters(value interface{}) ([]*dbx.Filter, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, unsupported("logical operator argument", value)
+	}
+	ret := make([]*dbx.Filter, v.Len())
+	for i := range ret {
+		f, err := toFilter(v.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = f
+	}
+	return ret, nil
+}
+
+// structFields returns the non-zero fields of a struct by their BSON key.
+func structFields(v reflect.Value) map[string]interface{} {
+	ret := map[string]interface{}{}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" || field.Tag.Get("bson") == "-" || v.Field(i).IsZero() {
+			continue
+		}
+		if strings.Contains(field.Tag.Get("bson"), ",inline") && reflect.Indirect(v.Field(i)).Kind() == reflect.Struct {
+			for k, value := range structFields(reflect.Indirect(v.Field(i))) {
+				ret[k] = value
+			}
+			continue
+		}
+		ret[tagName(field)] = v.Field(i).Interface()
+	}
+	return ret
+}
+
+// matcher evaluates the conditions of a query against documents.
+type matcher struct {
+	blocks [][]*condition
+}
+
+type condition struct {
+	filter  *dbx.Filter
+	negated bool
+}
+
+// newMatcher translates the condition blocks of a query. Conditions in the same block are joined with AND, and blocks
+// are joined with OR.
+func newMatcher(blocks [][]*common.ConditionData) (*matcher, error) {
+	ret := &matcher{}
+	for _, block := range blocks {
+		var conds []*condition
+		for _, c := range block {
+			if c.Query == nil {
+				continue
+			}
+			f, err := toFilter(c.Query)
+			if err != nil {
+				return nil, err
+			}
+			conds = append(conds, &condition{filter: f, negated: c.Negation})
+		}
+		if len(conds) > 0 {
+			ret.blocks = append(ret.blocks, conds)
+		}
+	}
+	return ret, nil
+}
+
+func (m *matcher) match(doc bson.M) (bool, error) {
+	if len(m.blocks) == 0 {
+		return true, nil
+	}
+	for _, block := range m.blocks {
+		ok := true
+		for _, c := range block {
+			matched, err := evaluate(doc, c.filter)
+			if err != nil {
+				return false, err
+			}
+			if matched == c.negated {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func evaluate(doc bson.M, f *dbx.Filter) (bool, error) {
+	if f == nil {
+		return true, nil
+	}
+
+	switch f.Op {
+	case dbx.FilterAnd:
+		for _, inner := range f.Filters {
+			if ok, err := evaluate(doc, inner); err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case dbx.FilterOr:
+		for _, inner := range f.Filters {
+			if ok, err := evaluate(doc, inner); err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case dbx.FilterNot:
+		ok, err := evaluate(doc, dbx.And(f.Filters...))
+		return !ok, err
+	}
+
+	value, found := lookup(doc, f.Field)
+
+	switch f.Op {
+	case dbx.FilterEq:
+		return equals(value, found, f.Value), nil
+	case dbx.FilterNe:
+		return !equals(value, found, f.Value), nil
+	case dbx.FilterIn, dbx.FilterNin:
+		in := false
+		for _, v := range f.Values() {
+			if equals(value, found, v) {
+				in = true
+				break
+			}
+		}
+		return in == (f.Op == dbx.FilterIn), nil
+	case dbx.FilterGt:
+		return anyValue(value, f.Value, func(c int) bool { return c > 0 }), nil
+	case dbx.FilterGte:
+		return anyValue(value, f.Value, func(c int) bool { return c >= 0 }), nil
+	case dbx.FilterLt:
+		return anyValue(value, f.Value, func(c int) bool { return c < 0 }), nil
+	case dbx.FilterLte:
+		return anyValue(value, f.Value, func(c int) bool { return c <= 0 }), nil
+	case dbx.FilterLike:
+		return matchRegex(value, "(?s)"+common.LikeToRegex(fmt.Sprint(f.Value)))
+	case dbx.FilterRegex:
+		return matchRegex(value, fmt.Sprint(f.Value))
+	case dbx.FilterExists:
+		exists, _ := f.Value.(bool)
+		return (found && value != nil) == exists, nil
+	}
+
+	return false, &dbx.DbError{
+		Message: fmt.Sprintf("unsupported filter operator '%s'", f.Op),
+		Code:    dbx.ErrDbOperation,
+	}
+}
+
+// lookup returns the value of a field in a document. Nested fields are separated by a dot. If an intermediate field
+// is an array, the values of the nested field in all its elements are returned as an array.
+func lookup(doc interface{}, field string) (interface{}, bool) {
+	current := doc
+	parts := strings.Split(field, ".")
+
+	for i, p := range parts {
+		switch v := current.(type) {
+		case bson.M:
+			next, ok := v[p]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			rest := strings.Join(parts[i:], ".")
+			var values []interface{}
+			for _, elem := range v {
+				if value, ok := lookup(elem, rest); ok {
+					values = append(values, value)
+				}
+			}
+			return values, len(values) > 0
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// equals indicates whether the value of a document field is equal to the expected value. Following MongoDB semantics,
+// a nil value matches missing fields and an array field matches if any of its elements is equal.
+func equals(value interface{}, found bool, expected interface{}) bool {
+	expected = normalize(expected)
+	if expected == nil {
+		return !found || value == nil
+	}
+	if compareEqual(value, expected) {
+		return true
+	}
+	if arr, ok := value.([]interface{}); ok {
+		for _, elem := range arr {
+			if compareEqual(elem, expected) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func compareEqual(a, b interface{}) bool {
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func anyValue(value, expected interface{}, fn func(int) bool) bool {
+	expected = normalize(expected)
+	if c, ok := compare(value, expected); ok && fn(c) {
+		return true
+	}
+	if arr, ok := value.([]interface{}); ok {
+		for _, elem := range arr {
+			if c, ok := compare(elem, expected); ok && fn(c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchRegex(value interface{}, pattern string) (bool, error) {
+	r, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, &dbx.DbError{
+			Message: fmt.Sprintf("invalid regular expression '%s', %s", pattern, err.Error()),
+			Code:    dbx.ErrDbOperation,
+		}
+	}
+	if s, ok := value.(string); ok {
+		return r.MatchString(s), nil
+	}
+	if arr, ok := value.([]interface{}); ok {
+		for _, elem := range arr {
+			if s, ok := elem.(string); ok && r.MatchString(s) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// compare compares two values of the same kind. Returns false if the values are not comparable.
+func compare(a, b interface{}) (int, bool) {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			return compareOrdered(x, y), true
+		}
+		return 0, false
+	}
+
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case bson.ObjectId:
+		if y, ok := b.(bson.ObjectId); ok {
+			return strings.Compare(string(x), string(y)), true
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			switch {
+			case x.Before(y):
+				return -1, true
+			case x.After(y):
+				return 1, true
+			}
+			return 0, true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, true
+			case y:
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, false
+}
+
+func compareOrdered(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// sortDocs sorts the provided documents by the given fields. A field prefixed by '-' is sorted in reverse order.
+// Missing and null values are sorted first, as MongoDB does.
+func sortDocs(docs []bson.M, fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, field := range fields {
+			desc := strings.HasPrefix(field, "-")
+			name := strings.TrimPrefix(strings.TrimPrefix(field, "-"), "+")
+			a, _ := lookup(docs[i], name)
+			b, _ := lookup(docs[j], name)
+			c := sortCompare(a, b)
+			if c == 0 {
+				continue
+			}
+			return c < 0 != desc
+		}
+		return false
+	})
+}
+
+func sortCompare(a, b interface{}) int {
+	if c, ok := compare(a, b); ok {
+		return c
+	}
+	return compareOrdered(float64(typeRank(a)), float64(typeRank(b)))
+}
+
+// typeRank is the order of the types when comparing values of different types, based on the MongoDB order.
+func typeRank(value interface{}) int {
+	if _, ok := toFloat(value); ok {
+		return 1
+	}
+	switch value.(type) {
+	case nil:
+		return 0
+	case string:
+		return 2
+	case bson.M:
+		return 3
+	case []interface{}:
+		return 4
+	case bson.ObjectId:
+		return 5
+	case bool:
+		return 6
+	case time.Time:
+		return 7
+	}
+	return 8
+}
+
+func unsupported(what string, value interface{}) error {
+	return &dbx.DbError{
+		Message: fmt.Sprintf("unsupported %s of type %T", what, value),
+		Code:    dbx.ErrDbOperation,
+	}
+}
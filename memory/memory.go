@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/logger"
+)
+
+// ProviderName is the name used to register the in-memory provider.
+const ProviderName = "Memory"
+
+var (
+	stores   = map[string]*store{}
+	storesMx sync.Mutex
+)
+
+type provider struct {
+}
+
+// Dial returns an in-memory database. Databases dialed with the same `cfg.Database` share the same data, which allows
+// a test to seed the data through its own instance. An empty database name always returns a new empty database.
+func (p *provider) Dial(cfg *dbx.DbConfig) (dbx.IDatabase, error) {
+	if cfg == nil || cfg.Database == "" {
+		return New(), nil
+	}
+
+	storesMx.Lock()
+	defer storesMx.Unlock()
+
+	s, ok := stores[cfg.Database]
+	if !ok {
+		s = newStore()
+		stores[cfg.Database] = s
+	}
+	return fromStore(s), nil
+}
+
+func init() {
+	if err := dbx.Register(ProviderName, &provider{}); err != nil {
+		logger.Get().Error("Unable to register Memory provider, ", err.Error())
+	}
+}
+
+// New creates a new empty in-memory database.
+func New() dbx.IDatabase {
+	return fromStore(newStore())
+}
+
+// Reset discards the data of all the in-memory databases obtained by `Dial` with a database name.
+func Reset() {
+	storesMx.Lock()
+	defer storesMx.Unlock()
+	stores = map[string]*store{}
+}
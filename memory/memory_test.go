@@ -0,0 +1,334 @@
+package memory
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/common"
+	"github.com/jucardi/go-db/pages"
+	. "github.com/jucardi/go-testx/testx"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type user struct {
+	ID     bson.ObjectId `bson:"_id,omitempty"`
+	Name   string        `bson:"name"`
+	Age    int           `bson:"age"`
+	Status string        `bson:"status"`
+	Tags   []string      `bson:"tags,omitempty"`
+}
+
+func seed(t *testing.T) dbx.IDatabase {
+	db := New()
+	err := db.R("users").Insert(
+		&user{Name: "alice", Age: 30, Status: "active", Tags: []string{"admin"}},
+		&user{Name: "bob", Age: 17, Status: "active"},
+		&user{Name: "carol", Age: 45, Status: "inactive", Tags: []string{"admin", "ops"}},
+		&user{Name: "dave", Age: 25, Status: "active", Tags: []string{"ops"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func names(users []*user) (ret []string) {
+	for _, u := range users {
+		ret = append(ret, u.Name)
+	}
+	return
+}
+
+func TestQuery(t *testing.T) {
+	db := seed(t)
+	users := db.R("users")
+
+	Convey("Insert assigns an identifier", t, func() {
+		u := &user{Name: "eve"}
+		ShouldBeNil(db.R("others").Insert(u))
+		ShouldBeTrue(u.ID.Valid())
+	})
+	Convey("Filters, sorting and pagination", t, func() {
+		var result []*user
+		ShouldBeNil(users.Where(dbx.And(dbx.Eq("status", "active"), dbx.Gte("age", 18))).Sort("-age").All(&result))
+		ShouldEqual([]string{"alice", "dave"}, names(result))
+
+		result = nil
+		ShouldBeNil(users.Where(dbx.Eq("tags", "ops")).Sort("name").Skip(1).Limit(1).All(&result))
+		ShouldEqual([]string{"dave"}, names(result))
+	})
+	Convey("Or and Not blocks", t, func() {
+		var result []*user
+		ShouldBeNil(users.Where(bson.M{"name": "bob"}).Or().Where(dbx.Like("name", "c%")).Sort("name").All(&result))
+		ShouldEqual([]string{"bob", "carol"}, names(result))
+
+		n, err := users.Not(dbx.In("name", []string{"alice", "bob"})).Count()
+		ShouldBeNil(err)
+		ShouldEqual(2, n)
+	})
+	Convey("First, Last and not found", t, func() {
+		u := &user{}
+		ShouldBeNil(users.Where(&user{Status: "active"}).Sort("age").Last(u))
+		ShouldEqual("alice", u.Name)
+
+		err := users.Where(dbx.Eq("name", "nobody")).First(u)
+		ShouldError(err)
+		ShouldBeTrue(err.(*dbx.DbError).IsNotFound())
+	})
+	Convey("Distinct and Select", t, func() {
+		var tags []string
+		ShouldBeNil(users.Where(nil).Distinct("tags", &tags))
+		ShouldMatchElements([]string{"admin", "ops"}, tags)
+
+		var docs []bson.M
+		ShouldBeNil(users.Where(dbx.Eq("name", "bob")).Select(bson.M{"name": 1}).All(&docs))
+		ShouldLen(docs, 1)
+		ShouldLen(docs[0], 2)
+	})
+	Convey("WrapPage", t, func() {
+		var result []*user
+		p, err := users.Where(nil).WrapPage(&result, &pages.Page{Page: 2, Size: 3, Sort: []string{"name"}})
+		ShouldBeNil(err)
+		ShouldEqual(4, p.TotalCount)
+		ShouldEqual(2, p.TotalPages)
+		ShouldEqual([]string{"dave"}, names(result))
+		ShouldEqual(pages.CountExact, p.CountMode)
+		ShouldBeFalse(p.HasNext)
+	})
+	Convey("WrapPage without counting", t, func() {
+		var result []*user
+		p, err := users.Where(nil).WrapPage(&result, &pages.Page{Page: 1, Size: 3, Sort: []string{"name"}, Count: pages.CountNone})
+		ShouldBeNil(err)
+		ShouldEqual([]string{"alice", "bob", "carol"}, names(result))
+		ShouldEqual(3, p.ItemsCount)
+		ShouldEqual(0, p.TotalCount)
+		ShouldBeTrue(p.HasNext)
+		ShouldEqual(pages.CountNone, p.CountMode)
+
+		result = nil
+		p, err = users.Where(nil).WrapPage(&result, &pages.Page{Page: 2, Size: 3, Sort: []string{"name"}, Count: pages.CountNone})
+		ShouldBeNil(err)
+		ShouldEqual([]string{"dave"}, names(result))
+		ShouldBeFalse(p.HasNext)
+	})
+	Convey("WrapPage with an estimated count falls back to the exact count", t, func() {
+		var result []*user
+		p, err := users.Where(dbx.Eq("status", "active")).WrapPage(&result, &pages.Page{Page: 1, Size: 2, Count: pages.CountEstimated})
+		ShouldBeNil(err)
+		ShouldEqual(3, p.TotalCount)
+		ShouldEqual(2, p.TotalPages)
+		ShouldEqual(pages.CountExact, p.CountMode)
+		ShouldBeFalse(p.HasNext)
+	})
+	Convey("WrapCursor", t, func() {
+		cursor := &pages.Cursor{Size: 2, Sort: []string{"-age"}}
+		var result []*user
+		p, err := users.Where(nil).WrapCursor(&result, cursor)
+		ShouldBeNil(err)
+		ShouldEqual(4, p.TotalCount)
+		ShouldEqual([]string{"carol", "alice"}, names(result))
+		ShouldEqual("", p.PrevCursor)
+
+		result = nil
+		cursor.Token = p.NextCursor
+		p, err = users.Where(nil).WrapCursor(&result, cursor)
+		ShouldBeNil(err)
+		ShouldEqual([]string{"dave", "bob"}, names(result))
+		ShouldEqual("", p.NextCursor)
+
+		result = nil
+		cursor.Token = p.PrevCursor
+		p, err = users.Where(nil).WrapCursor(&result, cursor)
+		ShouldBeNil(err)
+		ShouldEqual([]string{"carol", "alice"}, names(result))
+		ShouldEqual("", p.PrevCursor)
+		ShouldBeTrue(p.NextCursor != "")
+
+		cursor.Token = p.NextCursor[:len(p.NextCursor)-2] + "xx"
+		_, err = users.Where(nil).WrapCursor(&result, cursor)
+		ShouldEqual(pages.ErrInvalidCursor, err)
+
+		cursor.Token, cursor.Sort = p.NextCursor, []string{"name"}
+		_, err = users.Where(nil).WrapCursor(&result, cursor)
+		ShouldEqual(pages.ErrInvalidCursor, err)
+	})
+}
+
+func TestUpdateAndDelete(t *testing.T) {
+	db := seed(t)
+	users := db.R("users")
+
+	Convey("Update sets fields and applies operators", t, func() {
+		ShouldBeNil(users.Where(dbx.Eq("status", "inactive")).Update(bson.M{"$set": bson.M{"status": "active"}, "$inc": bson.M{"age": 1}}))
+		u := &user{}
+		ShouldBeNil(users.Where(dbx.Eq("name", "carol")).First(u))
+		ShouldEqual("active", u.Status)
+		ShouldEqual(46, u.Age)
+	})
+	Convey("Query updates and removals only apply to the first record, as in MongoDB", t, func() {
+		ShouldBeNil(users.Where(dbx.Eq("status", "active")).Sort("age").Update(bson.M{"$inc": bson.M{"age": 1}}))
+		u := &user{}
+		ShouldBeNil(users.Where(dbx.Eq("name", "bob")).First(u))
+		ShouldEqual(18, u.Age)
+		ShouldBeNil(users.Where(dbx.Eq("name", "dave")).First(u))
+		ShouldEqual(25, u.Age)
+
+		ShouldBeNil(users.Where(dbx.Eq("status", "active")).Sort("age").Remove())
+		n, _ := users.Where(dbx.Eq("status", "active")).Count()
+		ShouldEqual(3, n)
+		ShouldBeNil(users.Insert(&user{Name: "bob", Age: 17, Status: "active"}))
+
+		err := users.Where(dbx.Eq("name", "nobody")).Update(bson.M{"age": 1})
+		ShouldBeTrue(err.(*dbx.DbError).IsNotFound())
+		err = users.Where(dbx.Eq("name", "nobody")).Remove()
+		ShouldBeTrue(err.(*dbx.DbError).IsNotFound())
+	})
+	Convey("Delete removes the matched records", t, func() {
+		ShouldBeNil(users.Delete(dbx.Lt("age", 18)))
+		n, err := users.Where(nil).Count()
+		ShouldBeNil(err)
+		ShouldEqual(3, n)
+	})
+	Convey("Unique indexes are enforced", t, func() {
+		ShouldBeNil(users.AddUniqueIndex("name_idx", "name"))
+		ShouldError(users.Insert(&user{Name: "alice"}))
+		ShouldError(users.Where(dbx.Eq("name", "dave")).Update(bson.M{"name": "alice"}))
+	})
+}
+
+func TestTransactions(t *testing.T) {
+	db := seed(t)
+
+	Convey("Rolled back changes are discarded", t, func() {
+		err := db.WithTransaction(func(tx dbx.IDatabase) error {
+			if err := tx.R("users").Delete(nil); err != nil {
+				return err
+			}
+			return errors.New("abort")
+		})
+		ShouldError(err)
+		n, _ := db.R("users").Where(nil).Count()
+		ShouldEqual(4, n)
+	})
+	Convey("Committed changes are applied", t, func() {
+		err := db.WithTransaction(func(tx dbx.IDatabase) error {
+			return tx.R("users").Delete(dbx.Eq("name", "bob"))
+		})
+		ShouldBeNil(err)
+		n, _ := db.R("users").Where(nil).Count()
+		ShouldEqual(3, n)
+	})
+	Convey("Committing only replaces the repositories modified by the transaction", t, func() {
+		tx, err := db.Begin()
+		ShouldBeNil(err)
+		ShouldBeNil(tx.R("users").Insert(&user{Name: "frank"}))
+		ShouldBeNil(db.R("others").Insert(&user{Name: "grace"}))
+		ShouldBeNil(tx.Commit())
+
+		n, _ := db.R("users").Where(nil).Count()
+		ShouldEqual(4, n)
+		n, _ = db.R("others").Where(nil).Count()
+		ShouldEqual(1, n)
+	})
+	Convey("Dropping a missing index does not modify the transaction", t, func() {
+		tx, err := db.Begin()
+		ShouldBeNil(err)
+		ShouldBeNil(db.R("late").Insert(&user{Name: "heidi"}))
+		err = tx.R("late").DropIndex("idx_missing")
+		ShouldError(err)
+		ShouldBeTrue(err.(*dbx.DbError).IsNotFound())
+		ShouldBeNil(tx.Commit())
+
+		n, _ := db.R("late").Where(nil).Count()
+		ShouldEqual(1, n)
+	})
+}
+
+func TestMigrationLock(t *testing.T) {
+	db := New()
+
+	Convey("Concurrent migrations run each script once", t, func() {
+		var mx sync.Mutex
+		runs := 0
+		executor := func(string) error {
+			mx.Lock()
+			runs++
+			mx.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		}
+
+		errs := make(chan error, 3)
+		for i := 0; i < 3; i++ {
+			go func() {
+				migrator := &common.Migrator{
+					Db:             db,
+					DataDir:        "../common/test_assets/db_migration",
+					ScriptExecutor: executor,
+					UseLock:        true,
+				}
+				errs <- migrator.Migrate()
+			}()
+		}
+		for i := 0; i < 3; i++ {
+			ShouldBeNil(<-errs)
+		}
+		ShouldEqual(2, runs)
+
+		n, _ := db.R(common.MigrationRepo).Where(nil).Count()
+		ShouldEqual(2, n)
+		n, _ = db.R(common.MigrationRepo + "_lock").Where(nil).Count()
+		ShouldEqual(0, n)
+	})
+}
+
+type hookedUser struct {
+	Name  string   `bson:"name"`
+	Calls []string `bson:"-"`
+	Found bool     `bson:"-"`
+}
+
+func (u *hookedUser) BeforeCreate() error { u.Calls = append(u.Calls, "BeforeCreate"); return nil }
+func (u *hookedUser) AfterCreate() error  { u.Calls = append(u.Calls, "AfterCreate"); return nil }
+func (u *hookedUser) BeforeUpdate() error { u.Calls = append(u.Calls, "BeforeUpdate"); return nil }
+func (u *hookedUser) AfterUpdate() error  { u.Calls = append(u.Calls, "AfterUpdate"); return nil }
+
+func (u *hookedUser) AfterFound() error {
+	if u.Name == "invalid" {
+		return errors.New("invalid record")
+	}
+	u.Found = true
+	return nil
+}
+
+func TestEntityHooks(t *testing.T) {
+	users := New().R("users")
+
+	Convey("Create and update hooks are invoked around the operations", t, func() {
+		u := &hookedUser{Name: "alice"}
+		ShouldBeNil(users.Insert(u))
+		ShouldEqual([]string{"BeforeCreate", "AfterCreate"}, u.Calls)
+
+		update := &hookedUser{Name: "bob"}
+		ShouldBeNil(users.Where(dbx.Eq("name", "alice")).Update(update))
+		ShouldEqual([]string{"BeforeUpdate", "AfterUpdate"}, update.Calls)
+	})
+	Convey("AfterFound is invoked on every result", t, func() {
+		found := &hookedUser{}
+		ShouldBeNil(users.Where(dbx.Eq("name", "bob")).First(found))
+		ShouldBeTrue(found.Found)
+
+		var all []hookedUser
+		ShouldBeNil(users.Where(nil).All(&all))
+		ShouldLen(all, 1)
+		ShouldBeTrue(all[0].Found)
+	})
+	Convey("Hook errors abort the operation", t, func() {
+		ShouldBeNil(users.Insert(&hookedUser{Name: "invalid"}))
+		var all []*hookedUser
+		ShouldError(users.Where(nil).All(&all))
+	})
+}
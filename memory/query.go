@@ -0,0 +1,342 @@
+package memory
+
+import (
+	"reflect"
+
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/common"
+	"github.com/jucardi/go-db/entity"
+	"github.com/jucardi/go-db/pages"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type query struct {
+	*common.AbstractQuery
+	col *collection
+}
+
+func newQuery(c *collection) *query {
+	ret := &query{col: c}
+	ret.AbstractQuery = &common.AbstractQuery{
+		Q:   ret,
+		Ctx: c.ctx,
+	}
+	return ret
+}
+
+// Page adds to the query the information required to fetch the requested page of objects.
+func (q *query) Page(page ...*pages.Page) dbx.IQuery {
+	return common.PageHandler(q, page...)
+}
+
+// WrapPage attempts to obtain the items in the requested page and wraps the result in *pages.Paginated
+func (q *query) WrapPage(result interface{}, page ...*pages.Page) (*pages.Paginated, error) {
+	return common.WrapPageHandler(q, result, page...)
+}
+
+// Count returns the number of records that match the query conditions. Skip and Limit are applied to the count, as
+// done by the MongoDB provider.
+func (q *query) Count() (n int, err error) {
+	scope := q.newScope(dbx.OpQuery)
+	scope.Result = &n
+	err = q.col.db.callbacks.Run(scope, func() error {
+		return q.read(func(r *repo) error {
+			matched, err := q.match(r)
+			n = len(matched)
+			return err
+		})
+	})
+	return
+}
+
+func (q *query) First(result interface{}) error {
+	return q.one(result, false)
+}
+
+func (q *query) One(result interface{}) error {
+	return q.one(result, false)
+}
+
+func (q *query) Last(result interface{}) error {
+	return q.one(result, true)
+}
+
+func (q *query) All(result interface{}) error {
+	scope := q.newScope(dbx.OpQuery)
+	scope.Result = result
+	return q.col.db.callbacks.Run(scope, func() error {
+		if err := q.read(func(r *repo) error {
+			docs, err := q.find(r)
+			if err != nil {
+				return err
+			}
+			if docs, err = q.project(docs); err != nil {
+				return err
+			}
+			return decode(docs, result)
+		}); err != nil {
+			return err
+		}
+		return entity.InvokeResult(entity.MethodAfterFound, result)
+	})
+}
+
+// Distinct unmarshals into result the list of distinct values for the given key. Array values are unwound, as MongoDB
+// does.
+func (q *query) Distinct(key string, result interface{}) error {
+	scope := q.newScope(dbx.OpQuery)
+	scope.Result = result
+	return q.col.db.callbacks.Run(scope, func() error {
+		return q.read(func(r *repo) error {
+			docs, err := q.find(r)
+			if err != nil {
+				return err
+			}
+			var values []interface{}
+			add := func(v interface{}) {
+				for _, existing := range values {
+					if compareEqual(existing, v) {
+						return
+					}
+				}
+				values = append(values, v)
+			}
+			for _, d := range docs {
+				v, ok := lookup(d, key)
+				if !ok {
+					continue
+				}
+				if arr, isArr := v.([]interface{}); isArr {
+					for _, elem := range arr {
+						add(elem)
+					}
+				} else {
+					add(v)
+				}
+			}
+			return decode(values, result)
+		})
+	})
+}
+
+// Update updates the first record that matches the query, in sort order, as MongoDB does. Returns a not found error if
+// no record matches. The update may contain the MongoDB operators $set, $unset and $inc. Otherwise, the keys of a map
+// or the non-zero fields of a struct are set in the matched record.
+func (q *query) Update(update interface{}) error {
+	if err := entity.Invoke(entity.MethodBeforeUpdate, update); err != nil {
+		return err
+	}
+	scope := q.newScope(dbx.OpUpdate)
+	scope.Documents = []interface{}{update}
+	return q.col.db.callbacks.Run(scope, func() error {
+		if err := q.update(update); err != nil {
+			return err
+		}
+		return entity.Invoke(entity.MethodAfterUpdate, update)
+	})
+}
+
+func (q *query) update(update interface{}) error {
+	ops, err := toUpdate(update)
+	if err != nil {
+		return err
+	}
+	return q.write(func(r *repo) error {
+		matched, err := q.match(r)
+		if err != nil {
+			return err
+		}
+		if len(matched) == 0 {
+			return errNotFound()
+		}
+		all := append([]bson.M{}, r.docs...)
+		doc := copyDoc(all[matched[0]])
+		if err := ops.apply(doc); err != nil {
+			return err
+		}
+		all[matched[0]] = doc
+		if err := r.checkUnique(all); err != nil {
+			return err
+		}
+		r.docs = all
+		return nil
+	})
+}
+
+func (q *query) Delete() error {
+	return q.Remove()
+}
+
+// Remove removes the first record that matches the query, in sort order, as MongoDB does. Returns a not found error if
+// no record matches. Use `IRepository.Delete` to remove all the matching records.
+func (q *query) Remove() error {
+	return q.remove(false)
+}
+
+// remove removes the records that match the query, or only the first one if 'all' is false.
+func (q *query) remove(all bool) error {
+	return q.col.db.callbacks.Run(q.newScope(dbx.OpDelete), func() error {
+		return q.write(func(r *repo) error {
+			matched, err := q.match(r)
+			if err != nil {
+				return err
+			}
+			if !all {
+				if len(matched) == 0 {
+					return errNotFound()
+				}
+				matched = matched[:1]
+			}
+			remove := map[int]bool{}
+			for _, i := range matched {
+				remove[i] = true
+			}
+			var docs []bson.M
+			for i, d := range r.docs {
+				if !remove[i] {
+					docs = append(docs, d)
+				}
+			}
+			r.docs = docs
+			return nil
+		})
+	})
+}
+
+func (q *query) newScope(op dbx.Operation) *dbx.Scope {
+	return &dbx.Scope{
+		Context:   q.Ctx,
+		Operation: op,
+		Repo:      q.col.name,
+		Condition: q.Queries,
+	}
+}
+
+func (q *query) one(result interface{}, last bool) error {
+	scope := q.newScope(dbx.OpQuery)
+	scope.Result = result
+	return q.col.db.callbacks.Run(scope, func() error {
+		if err := q.read(func(r *repo) error {
+			docs, err := q.find(r)
+			if err != nil {
+				return err
+			}
+			if len(docs) == 0 {
+				return errNotFound()
+			}
+			doc := docs[0]
+			if last {
+				doc = docs[len(docs)-1]
+			}
+			projected, err := q.project([]bson.M{doc})
+			if err != nil {
+				return err
+			}
+			return decode(projected[0], result)
+		}); err != nil {
+			return err
+		}
+		return entity.InvokeResult(entity.MethodAfterFound, result)
+	})
+}
+
+// read runs the provided function with the repository of the query while holding a read lock. Nothing is done if
+// the repository does not exist.
+func (q *query) read(fn func(r *repo) error) error {
+	s := q.col.db.store
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	r := s.repo(q.col.name, false)
+	if r == nil {
+		r = newRepo()
+	}
+	return fn(r)
+}
+
+// write runs the provided function with the repository of the query while holding a write lock.
+func (q *query) write(fn func(r *repo) error) error {
+	s := q.col.db.store
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return fn(s.repo(q.col.name, true))
+}
+
+// match returns the positions of the records that match the query, sorted and paginated.
+func (q *query) match(r *repo) ([]int, error) {
+	m, err := newMatcher(q.Queries)
+	if err != nil {
+		return nil, err
+	}
+
+	var positions []int
+	for i, d := range r.docs {
+		ok, err := m.match(d)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			positions = append(positions, i)
+		}
+	}
+
+	if len(q.SortFields) > 0 {
+		docs := make([]bson.M, len(positions))
+		pos := make(map[uintptr]int, len(positions))
+		for i, p := range positions {
+			docs[i] = r.docs[p]
+			pos[reflect.ValueOf(r.docs[p]).Pointer()] = p
+		}
+		sortDocs(docs, q.SortFields)
+		for i, d := range docs {
+			positions[i] = pos[reflect.ValueOf(d).Pointer()]
+		}
+	}
+
+	if q.SkipVal != nil {
+		if *q.SkipVal >= len(positions) {
+			positions = nil
+		} else if *q.SkipVal > 0 {
+			positions = positions[*q.SkipVal:]
+		}
+	}
+	if q.LimitVal != nil && *q.LimitVal > 0 && *q.LimitVal < len(positions) {
+		positions = positions[:*q.LimitVal]
+	}
+	return positions, nil
+}
+
+// find returns the records that match the query, sorted and paginated.
+func (q *query) find(r *repo) ([]bson.M, error) {
+	positions, err := q.match(r)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]bson.M, len(positions))
+	for i, p := range positions {
+		ret[i] = r.docs[p]
+	}
+	return ret, nil
+}
+
+func (q *query) project(docs []bson.M) ([]bson.M, error) {
+	if len(q.Selects) == 0 {
+		return docs, nil
+	}
+	p, err := newProjection(q.Selects)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]bson.M, len(docs))
+	for i, d := range docs {
+		ret[i] = p.apply(d)
+	}
+	return ret, nil
+}
+
+func errNotFound() error {
+	return &dbx.DbError{
+		Message: "not found",
+		Code:    dbx.ErrNotFound,
+	}
+}
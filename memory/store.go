@@ -0,0 +1,235 @@
+package memory
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jucardi/go-db"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const idField = "_id"
+
+// store holds the repositories of an in-memory database.
+type store struct {
+	mx    sync.RWMutex
+	repos map[string]*repo
+
+	// touched are the names of the repositories modified in the snapshot of a transaction, nil otherwise.
+	touched map[string]bool
+}
+
+// repo holds the documents of a repository in insertion order.
+type repo struct {
+	docs    []bson.M
+	indexes map[string]*index
+}
+
+type index struct {
+	fields []string
+	unique bool
+}
+
+func newStore() *store {
+	return &store{repos: map[string]*repo{}}
+}
+
+func newRepo() *repo {
+	return &repo{indexes: map[string]*index{}}
+}
+
+// snapshot returns a deep copy of the store, used as the working copy of a transaction.
+func (s *store) snapshot() *store {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	ret := newStore()
+	ret.touched = map[string]bool{}
+	for name, r := range s.repos {
+		ret.repos[name] = r.clone()
+	}
+	return ret
+}
+
+// repo returns the repository with the given name. If 'create' is true, the repository is created if it does not exist,
+// and it is marked as modified, since it is only requested for writing. Must be called while holding the lock.
+func (s *store) repo(name string, create bool) *repo {
+	r, ok := s.repos[name]
+	if !ok && create {
+		r = newRepo()
+		s.repos[name] = r
+	}
+	if create {
+		s.touch(name)
+	}
+	return r
+}
+
+// touch marks the repository as modified if the store is the snapshot of a transaction. Must be called while holding
+// the lock.
+func (s *store) touch(name string) {
+	if s.touched != nil {
+		s.touched[name] = true
+	}
+}
+
+// merge replaces the repositories of the store with the ones modified in the provided snapshot. Must be called while
+// holding the lock of both stores.
+func (s *store) merge(snapshot *store) {
+	for name := range snapshot.touched {
+		if r, ok := snapshot.repos[name]; ok {
+			s.repos[name] = r
+		} else {
+			delete(s.repos, name)
+		}
+		s.touch(name)
+	}
+}
+
+func (r *repo) clone() *repo {
+	ret := newRepo()
+	ret.docs = make([]bson.M, len(r.docs))
+	for i, d := range r.docs {
+		ret.docs[i] = copyDoc(d)
+	}
+	for name, idx := range r.indexes {
+		ret.indexes[name] = idx
+	}
+	return ret
+}
+
+// checkUnique validates that the provided documents do not violate any of the unique indexes.
+func (r *repo) checkUnique(docs []bson.M) error {
+	for name, idx := range r.indexes {
+		if !idx.unique {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, d := range docs {
+			key := indexKey(d, idx.fields)
+			if seen[key] {
+				return &dbx.DbError{
+					Message: fmt.Sprintf("duplicate key error, index: %s, key: %s", name, key),
+					Code:    dbx.ErrDbOperation,
+				}
+			}
+			seen[key] = true
+		}
+	}
+	return nil
+}
+
+func indexKey(doc bson.M, fields []string) string {
+	values := make([]string, len(fields))
+	for i, f := range fields {
+		v, _ := lookup(doc, f)
+		if n, ok := toFloat(v); ok {
+			v = n
+		}
+		values[i] = fmt.Sprintf("%#v", v)
+	}
+	return strings.Join(values, ", ")
+}
+
+// toDoc converts the provided value (struct or map) into a document, by marshaling and unmarshaling it as BSON.
+func toDoc(value interface{}) (bson.M, error) {
+	data, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	ret := bson.M{}
+	if err := bson.Unmarshal(data, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func copyDoc(doc bson.M) bson.M {
+	ret, err := toDoc(doc)
+	if err != nil {
+		// Stored documents are always the result of a BSON round trip, so this should not happen.
+		panic(err)
+	}
+	return ret
+}
+
+// normalize converts a value into its BSON representation, so it can be compared with the values of a document.
+func normalize(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	doc, err := toDoc(bson.M{"v": value})
+	if err != nil {
+		return value
+	}
+	return doc["v"]
+}
+
+// decode unmarshals the provided value into 'out', which must be a pointer.
+func decode(value interface{}, out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &dbx.DbError{
+			Message: fmt.Sprintf("the result argument must be a non nil pointer, found %T", out),
+			Code:    dbx.ErrDbOperation,
+		}
+	}
+
+	wrapper := reflect.New(reflect.StructOf([]reflect.StructField{{
+		Name: "V",
+		Type: rv.Elem().Type(),
+		Tag:  `bson:"v"`,
+	}}))
+
+	data, err := bson.Marshal(bson.M{"v": value})
+	if err != nil {
+		return err
+	}
+	if err := bson.Unmarshal(data, wrapper.Interface()); err != nil {
+		return err
+	}
+	rv.Elem().Set(wrapper.Elem().Field(0))
+	return nil
+}
+
+// assignId generates an identifier for the document if it does not have one. The identifier is also set in the
+// original value if it is a struct with an empty `bson:"_id"` field of type bson.ObjectId.
+func assignId(doc bson.M, original interface{}) {
+	if id, ok := doc[idField]; ok && id != nil && id != "" {
+		return
+	}
+	id := bson.NewObjectId()
+	doc[idField] = id
+
+	v := reflect.ValueOf(original)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return
+	}
+	v = v.Elem()
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if tagName(v.Type().Field(i)) != idField {
+			continue
+		}
+		if f := v.Field(i); f.CanSet() && f.Type() == reflect.TypeOf(id) && f.String() == "" {
+			f.Set(reflect.ValueOf(id))
+		}
+	}
+}
+
+// tagName returns the BSON key of a struct field, following the same rules as `gopkg.in/mgo.v2/bson`.
+func tagName(field reflect.StructField) string {
+	tag := field.Tag.Get("bson")
+	if tag == "" && !strings.Contains(string(field.Tag), ":") {
+		tag = string(field.Tag)
+	}
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
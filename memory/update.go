@@ -0,0 +1,217 @@
+package memory
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/common"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// updateOps contains the changes to apply to the records matched by an update.
+type updateOps struct {
+	set   bson.M
+	unset []string
+	inc   bson.M
+}
+
+func toUpdate(update interface{}) (*updateOps, error) {
+	v := reflect.Indirect(reflect.ValueOf(update))
+
+	var doc bson.M
+	switch v.Kind() {
+	case reflect.Struct:
+		doc = bson.M{}
+		for k, value := range structFields(v) {
+			doc[k] = normalize(value)
+		}
+		return &updateOps{set: doc}, nil
+	case reflect.Map:
+		var err error
+		if doc, err = toDoc(update); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, unsupported("update", update)
+	}
+
+	ret := &updateOps{set: bson.M{}, inc: bson.M{}}
+	for k, value := range doc {
+		if !strings.HasPrefix(k, "$") {
+			ret.set[k] = value
+			continue
+		}
+		fields, ok := value.(bson.M)
+		if !ok {
+			return nil, unsupported(fmt.Sprintf("'%s' argument", k), value)
+		}
+		switch k {
+		case "$set":
+			for f, fv := range fields {
+				ret.set[f] = fv
+			}
+		case "$unset":
+			for f := range fields {
+				ret.unset = append(ret.unset, f)
+			}
+		case "$inc":
+			for f, fv := range fields {
+				ret.inc[f] = fv
+			}
+		default:
+			return nil, &dbx.DbError{
+				Message: fmt.Sprintf("unsupported update operator '%s'", k),
+				Code:    dbx.ErrDbOperation,
+			}
+		}
+	}
+	return ret, nil
+}
+
+func (u *updateOps) apply(doc bson.M) error {
+	for f, v := range u.set {
+		setPath(doc, f, v)
+	}
+	for _, f := range u.unset {
+		unsetPath(doc, f)
+	}
+	for f, v := range u.inc {
+		current, _ := lookup(doc, f)
+		sum, err := add(current, v)
+		if err != nil {
+			return err
+		}
+		setPath(doc, f, sum)
+	}
+	return nil
+}
+
+func setPath(doc bson.M, field string, value interface{}) {
+	parts := strings.Split(field, ".")
+	current := doc
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := current[p].(bson.M)
+		if !ok {
+			next = bson.M{}
+			current[p] = next
+		}
+		current = next
+	}
+	current[parts[len(parts)-1]] = value
+}
+
+func unsetPath(doc bson.M, field string) {
+	parts := strings.Split(field, ".")
+	current := doc
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := current[p].(bson.M)
+		if !ok {
+			return
+		}
+		current = next
+	}
+	delete(current, parts[len(parts)-1])
+}
+
+func add(current, inc interface{}) (interface{}, error) {
+	if current == nil {
+		current = 0
+	}
+	switch x := current.(type) {
+	case int:
+		switch y := inc.(type) {
+		case int:
+			return x + y, nil
+		case int64:
+			return int64(x) + y, nil
+		}
+	case int64:
+		switch y := inc.(type) {
+		case int:
+			return x + int64(y), nil
+		case int64:
+			return x + y, nil
+		}
+	}
+	a, ok1 := toFloat(current)
+	b, ok2 := toFloat(inc)
+	if !ok1 || !ok2 {
+		return nil, &dbx.DbError{
+			Message: fmt.Sprintf("cannot increment a value of type %T by a value of type %T", current, inc),
+			Code:    dbx.ErrDbOperation,
+		}
+	}
+	return a + b, nil
+}
+
+// projection restricts the fields of the returned records.
+type projection struct {
+	include map[string]bool
+	exclude map[string]bool
+}
+
+// newProjection builds a projection from the selected fields of a query. Accepts a map of fields (e.g.
+// bson.M{"name": 1} or bson.M{"password": 0}), a comma separated string of fields or a slice of fields.
+func newProjection(selects []*common.ConditionData) (*projection, error) {
+	ret := &projection{include: map[string]bool{}, exclude: map[string]bool{}}
+
+	for _, s := range selects {
+		v := reflect.ValueOf(s.Query)
+		switch {
+		case v.Kind() == reflect.String:
+			for _, f := range strings.Split(v.String(), ",") {
+				if f = strings.TrimSpace(f); f != "" {
+					ret.include[f] = true
+				}
+			}
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+			for i := 0; i < v.Len(); i++ {
+				ret.include[v.Index(i).String()] = true
+			}
+		case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+			for _, k := range v.MapKeys() {
+				if truthy(v.MapIndex(k).Interface()) {
+					ret.include[k.String()] = true
+				} else {
+					ret.exclude[k.String()] = true
+				}
+			}
+		default:
+			return nil, unsupported("select", s.Query)
+		}
+	}
+	return ret, nil
+}
+
+func (p *projection) apply(doc bson.M) bson.M {
+	ret := bson.M{}
+	if len(p.include) > 0 {
+		if !p.exclude[idField] {
+			if id, ok := doc[idField]; ok {
+				ret[idField] = id
+			}
+		}
+		for f := range p.include {
+			if v, ok := lookup(doc, f); ok {
+				setPath(ret, f, v)
+			}
+		}
+		return ret
+	}
+
+	ret = copyDoc(doc)
+	for f := range p.exclude {
+		unsetPath(ret, f)
+	}
+	return ret
+}
+
+func truthy(value interface{}) bool {
+	if b, ok := value.(bool); ok {
+		return b
+	}
+	n, ok := toFloat(normalize(value))
+	return !ok || n != 0
+}
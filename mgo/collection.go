@@ -1,7 +1,10 @@
 package mgo
 
 import (
+	"context"
+
 	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/common"
 	"github.com/jucardi/go-db/entity"
 	"github.com/jucardi/go-streams/streams"
 	"gopkg.in/mgo.v2"
@@ -101,6 +104,25 @@ var _ ICollection = (*collection)(nil)
 // collection is the default implementation of ICollection
 type collection struct {
 	*mgo.Collection
+	inTx      bool
+	ctx       context.Context
+	guard     *contextGuard
+	callbacks *common.CallbacksManager
+}
+
+// WithContext returns a copy of the collection bound to the provided context. As with `IDatabase.WithContext`, if the
+// context has a deadline the returned collection uses a dedicated copy of the session, released once the context is
+// done.
+func (c *collection) WithContext(ctx context.Context) dbx.IRepository {
+	ret := *c
+	ret.ctx = ctx
+	if !c.inTx {
+		if s, guard := deadlineSession(ctx, fromSession(c.C().Database.Session)); s != nil {
+			ret.Collection = c.C().With(s.S())
+			ret.guard = guard
+		}
+	}
+	return &ret
 }
 
 func (c *collection) Drop() error {
@@ -108,11 +130,11 @@ func (c *collection) Drop() error {
 }
 
 func (c *collection) Where(condition interface{}, args ...interface{}) dbx.IQuery {
-	return newQuery(c.C(), condition, false)
+	return newQuery(c, condition, false)
 }
 
 func (c *collection) Not(condition interface{}, args ...interface{}) dbx.IQuery {
-	return newQuery(c.C(), condition, true)
+	return newQuery(c, condition, true)
 }
 
 func (c *collection) AddIndex(indexName string, fields ...string) error {
@@ -142,11 +164,11 @@ func (c *collection) With(s ISession) ICollection {
 }
 
 func (c *collection) Find(query interface{}) IQuery {
-	return newQuery(c.C(), query, false)
+	return newQuery(c, query, false)
 }
 
 func (c *collection) FindId(id interface{}) IQuery {
-	return newQuery(c.C(), bson.M{"_id": id}, false)
+	return newQuery(c, bson.M{"_id": id}, false)
 }
 
 func (c *collection) EnsureIndex(index Index) error {
@@ -158,8 +180,16 @@ func (c *collection) DropIndexByKeys(key ...string) error {
 }
 
 func (c *collection) Delete(query interface{}, args ...interface{}) error {
-	_, err := c.RemoveAll(query)
-	return err
+	scope := &dbx.Scope{
+		Context:   c.ctx,
+		Operation: dbx.OpDelete,
+		Repo:      c.Name(),
+		Condition: query,
+	}
+	return c.callbacks.Run(scope, c.guard.wrap(func() error {
+		_, err := c.RemoveAll(toBson(query))
+		return err
+	}))
 }
 
 func (c *collection) RemoveAll(selector interface{}) (*ChangeInfo, error) {
@@ -167,19 +197,48 @@ func (c *collection) RemoveAll(selector interface{}) (*ChangeInfo, error) {
 	return makeChangeInfo(info), err
 }
 
+func (c *collection) Update(selector interface{}, update interface{}) error {
+	_, err := c.updateWithHooks(update, func() (*mgo.ChangeInfo, error) {
+		return nil, c.C().Update(selector, update)
+	})
+	return err
+}
+
+func (c *collection) UpdateId(id interface{}, update interface{}) error {
+	_, err := c.updateWithHooks(update, func() (*mgo.ChangeInfo, error) {
+		return nil, c.C().UpdateId(id, update)
+	})
+	return err
+}
+
 func (c *collection) UpsertId(id interface{}, update interface{}) (*ChangeInfo, error) {
-	info, err := c.C().UpsertId(id, update)
-	return makeChangeInfo(info), err
+	return c.updateWithHooks(update, func() (*mgo.ChangeInfo, error) {
+		return c.C().UpsertId(id, update)
+	})
 }
 
 func (c *collection) Upsert(selector interface{}, update interface{}) (*ChangeInfo, error) {
-	info, err := c.C().Upsert(selector, update)
-	return makeChangeInfo(info), err
+	return c.updateWithHooks(update, func() (*mgo.ChangeInfo, error) {
+		return c.C().Upsert(selector, update)
+	})
 }
 
 func (c *collection) UpdateAll(selector interface{}, update interface{}) (*ChangeInfo, error) {
-	info, err := c.C().UpdateAll(selector, update)
-	return makeChangeInfo(info), err
+	return c.updateWithHooks(update, func() (*mgo.ChangeInfo, error) {
+		return c.C().UpdateAll(selector, update)
+	})
+}
+
+// updateWithHooks runs an update operation between the BeforeUpdate and AfterUpdate hooks of the update document.
+func (c *collection) updateWithHooks(update interface{}, fn func() (*mgo.ChangeInfo, error)) (*ChangeInfo, error) {
+	if err := entity.Invoke(entity.MethodBeforeUpdate, update); err != nil {
+		return nil, err
+	}
+	info, err := fn()
+	if err != nil {
+		return makeChangeInfo(info), err
+	}
+	return makeChangeInfo(info), entity.Invoke(entity.MethodAfterUpdate, update)
 }
 
 func (c *collection) NewIter(session ISession, firstBatch []bson.Raw, cursorId int64, err error) IIter {
@@ -228,11 +287,22 @@ func (c *collection) Insert(docs ...interface{}) error {
 	if err := entity.Invoke(entity.MethodBeforeCreate, docs...); err != nil {
 		return err
 	}
-	if len(docs) < mgoLim {
-		return c.C().Insert(docs...)
+	scope := &dbx.Scope{
+		Context:   c.ctx,
+		Operation: dbx.OpCreate,
+		Repo:      c.Name(),
+		Documents: docs,
 	}
-	_, err := NewBulk(c).Insert(docs...).Run()
-	return err
+	return c.callbacks.Run(scope, c.guard.wrap(func() error {
+		if len(docs) < mgoLim {
+			if err := c.C().Insert(docs...); err != nil {
+				return err
+			}
+		} else if _, err := NewBulk(c).Insert(docs...).Run(); err != nil {
+			return err
+		}
+		return entity.Invoke(entity.MethodAfterCreate, docs...)
+	}))
 }
 
 // BulkUpsert allows multiple Upsert operations. Queues up the provided pairs of upserting instructions.
@@ -249,9 +319,15 @@ func (c *collection) update(col *mgo.Collection) ICollection {
 	return c
 }
 
-func fromCollection(col *mgo.Collection) ICollection {
+func fromCollection(col *mgo.Collection, db *database) ICollection {
 	if col == nil {
 		return nil
 	}
-	return &collection{Collection: col}
+	return &collection{
+		Collection: col,
+		inTx:       db.inTx,
+		ctx:        db.ctx,
+		guard:      db.guard,
+		callbacks:  db.callbacks,
+	}
 }
@@ -0,0 +1,59 @@
+package mgo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// contextGuard binds a dedicated copy of a session to a context. The session is closed once the context is done, after
+// any operation in progress finishes, so no operation runs on a closed session.
+type contextGuard struct {
+	mx  sync.RWMutex
+	ctx context.Context
+}
+
+func newContextGuard(ctx context.Context, s ISession) *contextGuard {
+	g := &contextGuard{ctx: ctx}
+	go func() {
+		<-ctx.Done()
+		g.mx.Lock()
+		defer g.mx.Unlock()
+		s.Close()
+	}()
+	return g
+}
+
+// wrap returns an action which fails with the context error if the context is done, or otherwise runs the provided
+// action preventing the session from being closed while it runs.
+func (g *contextGuard) wrap(action func() error) func() error {
+	if g == nil {
+		return action
+	}
+	return func() error {
+		g.mx.RLock()
+		defer g.mx.RUnlock()
+		if err := g.ctx.Err(); err != nil {
+			return err
+		}
+		return action()
+	}
+}
+
+// deadlineSession returns a copy of the session with its sync and socket timeouts set to the time remaining until the
+// deadline of the context, and the guard which releases the copy once the context is done. Returns a nil session if
+// the context has no deadline or it already passed.
+func deadlineSession(ctx context.Context, s ISession) (ISession, *contextGuard) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil, nil
+	}
+	timeout := time.Until(deadline)
+	if timeout <= 0 {
+		return nil, nil
+	}
+	ret := s.Copy()
+	ret.SetSyncTimeout(timeout)
+	ret.SetSocketTimeout(timeout)
+	return ret, newContextGuard(ctx, ret)
+}
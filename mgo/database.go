@@ -1,6 +1,9 @@
 package mgo
 
 import (
+	"context"
+	"io/fs"
+
 	"github.com/jucardi/go-db"
 	"github.com/jucardi/go-db/common"
 	l "github.com/jucardi/go-db/logger"
@@ -10,6 +13,9 @@ import (
 	"gopkg.in/mgo.v2/bson"
 )
 
+// IDatabase is the MongoDB implementation of dbx.IDatabase. Transactions are best-effort, since mgo does not support
+// multi-document transactions: writes are applied as they are executed and Rollback only releases the session bound to
+// the transaction, without reverting them, and returns `dbx.ErrRollbackNotSupported`.
 type IDatabase interface {
 	dbx.IDatabase
 
@@ -61,19 +67,80 @@ type IDatabase interface {
 
 type database struct {
 	*mgo.Database
-	executor dbx.ScriptExecutor
+	inTx      bool
+	ctx       context.Context
+	guard     *contextGuard
+	executor  dbx.ScriptExecutor
+	callbacks *common.CallbacksManager
 }
 
 func (d *database) Clone() dbx.IDatabase {
-	return d.Session().Clone().DB(d.Name())
+	ret := d.derive(d.Session().Clone().S().DB(d.Name()))
+	ret.inTx = false
+	ret.executor = nil
+	return ret
+}
+
+// WithContext returns a copy of the database bound to the provided context. If the context has a deadline, the returned
+// database uses a dedicated copy of the session with its sync and socket timeouts set to the time remaining until the
+// deadline. The copy of the session is released once the context is done.
+func (d *database) WithContext(ctx context.Context) dbx.IDatabase {
+	ret := d.derive(d.Database)
+	ret.ctx = ctx
+
+	if !d.inTx {
+		if s, guard := deadlineSession(ctx, d.Session()); s != nil {
+			ret.Database = d.DB().With(s.S())
+			ret.guard = guard
+		}
+	}
+	return ret
 }
 
 func (d *database) Close() {
 	d.Session().Close()
 }
 
+// Begin begins a best-effort transaction. MongoDB multi-document transactions are not supported by mgo, so the returned
+// transaction is bound to a dedicated copy of the session in strong consistency mode with acknowledged writes. Writes
+// are applied as they are executed and are not reverted by Rollback.
+func (d *database) Begin() (dbx.ITransaction, error) {
+	if d.ctx != nil && d.ctx.Err() != nil {
+		return nil, d.ctx.Err()
+	}
+	s := d.Session().Copy()
+	s.SetMode(mgo.Strong, true)
+	s.EnsureSafe(&mgo.Safe{})
+	ret := d.derive(d.DB().With(s.S()))
+	ret.inTx = true
+	return ret, nil
+}
+
+// Commit ends the transaction and releases the session bound to it.
+func (d *database) Commit() error {
+	if !d.inTx {
+		return dbx.ErrNotInTransaction
+	}
+	d.Session().Close()
+	return nil
+}
+
+// Rollback ends the transaction and releases the session bound to it. Since mgo does not support transactions, the
+// changes made during the transaction are not reverted and `dbx.ErrRollbackNotSupported` is returned to indicate so.
+func (d *database) Rollback() error {
+	if !d.inTx {
+		return dbx.ErrNotInTransaction
+	}
+	d.Session().Close()
+	return dbx.ErrRollbackNotSupported
+}
+
+func (d *database) WithTransaction(fn func(tx dbx.IDatabase) error) error {
+	return common.TransactionHandler(d, fn)
+}
+
 func (d *database) Callbacks() dbx.ICallbacksManager {
-	panic("implement me")
+	return d.callbacks
 }
 
 func (d *database) SetLogger(l log.ILogger) {
@@ -94,18 +161,24 @@ func (d *database) Collection(name string) ICollection {
 }
 
 func (d *database) C(name string) ICollection {
-	return fromCollection(d.DB().C(name))
+	return fromCollection(d.DB().C(name), d)
 }
 
 func (d *database) Exec(script string, result interface{}) error {
-	return d.DB().Run(bson.M{"eval": script}, result)
+	return d.run(func() error {
+		return d.DB().Run(bson.M{"eval": script}, result)
+	})
 }
 
 func (d *database) Run(script string) error {
 	if d.executor != nil {
-		return d.executor(script)
+		return d.run(func() error {
+			return d.executor(script)
+		})
 	}
-	return d.DB().Run(bson.M{"eval": script}, nil)
+	return d.run(func() error {
+		return d.DB().Run(bson.M{"eval": script}, nil)
+	})
 }
 
 func (d *database) CreateRepo(name string, models ...interface{}) error {
@@ -126,7 +199,28 @@ func (d *database) Migrate(dataDir string, failOnOrderMismatch ...bool) error {
 	if len(failOnOrderMismatch) > 0 {
 		fail = failOnOrderMismatch[0]
 	}
-	return common.Migrate(dataDir, d, fail)
+	migrator := &common.Migrator{
+		Db:                  d,
+		DataDir:             dataDir,
+		FailOnOrderMismatch: fail,
+		Context:             d.ctx,
+	}
+	return migrator.Migrate()
+}
+
+func (d *database) MigrateFS(fsys fs.FS, dir string, failOnOrderMismatch ...bool) error {
+	fail := true
+	if len(failOnOrderMismatch) > 0 {
+		fail = failOnOrderMismatch[0]
+	}
+	migrator := &common.Migrator{
+		Db:                  d,
+		FS:                  fsys,
+		DataDir:             dir,
+		FailOnOrderMismatch: fail,
+		Context:             d.ctx,
+	}
+	return migrator.Migrate()
 }
 
 func (d *database) SetScriptExecutor(executor dbx.ScriptExecutor) {
@@ -138,7 +232,9 @@ func (d *database) DB() *mgo.Database {
 }
 
 func (d *database) With(s ISession) IDatabase {
-	return FromDB(d.DB().With(s.S()))
+	ret := d.derive(d.DB().With(s.S()))
+	ret.guard = nil
+	return ret
 }
 
 func (d *database) FindRef(ref *mgo.DBRef) IQuery {
@@ -163,5 +259,23 @@ func FromDB(db *mgo.Database) IDatabase {
 	if db == nil {
 		return nil
 	}
-	return &database{Database: db}
+	return &database{
+		Database:  db,
+		callbacks: common.NewCallbacksManager(),
+	}
+}
+
+// derive returns a shallow copy of the database using the provided *mgo.Database
+func (d *database) derive(db *mgo.Database) *database {
+	ret := *d
+	ret.Database = db
+	return &ret
+}
+
+// run runs the provided action, aborting it if the database is bound to a context which is done.
+func (d *database) run(action func() error) error {
+	if d.ctx != nil && d.ctx.Err() != nil {
+		return d.ctx.Err()
+	}
+	return d.guard.wrap(action)()
 }
@@ -0,0 +1,81 @@
+package mgo
+
+import (
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/common"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// toBson translates a query condition into its BSON representation. Conditions other than *dbx.Filter are returned as
+// they are, since they are assumed to be valid mgo selectors.
+func toBson(condition interface{}) interface{} {
+	if f, ok := condition.(*dbx.Filter); ok {
+		return filterToBson(f)
+	}
+	return condition
+}
+
+func filterToBson(f *dbx.Filter) bson.M {
+	if f == nil {
+		return bson.M{}
+	}
+
+	switch f.Op {
+	case dbx.FilterEq:
+		return bson.M{f.Field: f.Value}
+	case dbx.FilterNe:
+		return bson.M{f.Field: bson.M{"$ne": f.Value}}
+	case dbx.FilterIn:
+		return bson.M{f.Field: bson.M{"$in": values(f)}}
+	case dbx.FilterNin:
+		return bson.M{f.Field: bson.M{"$nin": values(f)}}
+	case dbx.FilterGt:
+		return bson.M{f.Field: bson.M{"$gt": f.Value}}
+	case dbx.FilterGte:
+		return bson.M{f.Field: bson.M{"$gte": f.Value}}
+	case dbx.FilterLt:
+		return bson.M{f.Field: bson.M{"$lt": f.Value}}
+	case dbx.FilterLte:
+		return bson.M{f.Field: bson.M{"$lte": f.Value}}
+	case dbx.FilterLike:
+		return bson.M{f.Field: bson.RegEx{Pattern: common.LikeToRegex(f.Value.(string)), Options: "s"}}
+	case dbx.FilterRegex:
+		return bson.M{f.Field: bson.RegEx{Pattern: f.Value.(string)}}
+	case dbx.FilterExists:
+		if exists, _ := f.Value.(bool); exists {
+			return bson.M{f.Field: bson.M{"$ne": nil}}
+		}
+		return bson.M{f.Field: nil}
+	case dbx.FilterAnd:
+		if len(f.Filters) == 0 {
+			return bson.M{}
+		}
+		return bson.M{"$and": filtersToBson(f.Filters)}
+	case dbx.FilterOr:
+		if len(f.Filters) == 0 {
+			// An empty $or is rejected by the server, '_id' is always present, so this matches nothing.
+			return bson.M{"_id": bson.M{"$exists": false}}
+		}
+		return bson.M{"$or": filtersToBson(f.Filters)}
+	case dbx.FilterNot:
+		return bson.M{"$nor": filtersToBson(f.Filters)}
+	}
+
+	return bson.M{f.Field: f.Value}
+}
+
+func filtersToBson(filters []*dbx.Filter) []interface{} {
+	ret := make([]interface{}, len(filters))
+	for i, f := range filters {
+		ret[i] = filterToBson(f)
+	}
+	return ret
+}
+
+// values returns the values of an In or Nin filter, never nil since the server requires an array.
+func values(f *dbx.Filter) []interface{} {
+	if ret := f.Values(); ret != nil {
+		return ret
+	}
+	return []interface{}{}
+}
@@ -0,0 +1,50 @@
+package mgo
+
+import (
+	"testing"
+
+	"github.com/jucardi/go-db"
+	. "github.com/jucardi/go-testx/testx"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestFilterToBson(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter *dbx.Filter
+		bson   bson.M
+	}{
+		{"Eq", dbx.Eq("name", "bob"), bson.M{"name": "bob"}},
+		{"Eq nil", dbx.Eq("name", nil), bson.M{"name": nil}},
+		{"Ne", dbx.Ne("name", "bob"), bson.M{"name": bson.M{"$ne": "bob"}}},
+		{"Ne nil", dbx.Ne("name", nil), bson.M{"name": bson.M{"$ne": nil}}},
+		{"Gt", dbx.Gt("age", 1), bson.M{"age": bson.M{"$gt": 1}}},
+		{"Gte", dbx.Gte("age", 1), bson.M{"age": bson.M{"$gte": 1}}},
+		{"Lt", dbx.Lt("age", 1), bson.M{"age": bson.M{"$lt": 1}}},
+		{"Lte", dbx.Lte("age", 1), bson.M{"age": bson.M{"$lte": 1}}},
+		{"Like", dbx.Like("name", `50\%%`), bson.M{"name": bson.RegEx{Pattern: "^50%.*$", Options: "s"}}},
+		{"Regex", dbx.Regex("name", "^b"), bson.M{"name": bson.RegEx{Pattern: "^b"}}},
+		{"In", dbx.In("age", []int{1, 2}), bson.M{"age": bson.M{"$in": []interface{}{1, 2}}}},
+		{"Empty In", dbx.In("age"), bson.M{"age": bson.M{"$in": []interface{}{}}}},
+		{"Nin", dbx.Nin("age", 1, 2), bson.M{"age": bson.M{"$nin": []interface{}{1, 2}}}},
+		{"Empty Nin", dbx.Nin("age"), bson.M{"age": bson.M{"$nin": []interface{}{}}}},
+		{"Exists", dbx.Exists("email"), bson.M{"email": bson.M{"$ne": nil}}},
+		{"Not exists", dbx.Exists("email", false), bson.M{"email": nil}},
+		{"Not", dbx.Not(dbx.Eq("name", "bob")), bson.M{"$nor": []interface{}{bson.M{"name": "bob"}}}},
+		{"Empty And", dbx.And(), bson.M{}},
+		{"Empty Or", dbx.Or(), bson.M{"_id": bson.M{"$exists": false}}},
+		{"Nested", dbx.And(dbx.Eq("status", "active"), dbx.Or(dbx.Lt("age", 18), dbx.Exists("guardian"))),
+			bson.M{"$and": []interface{}{
+				bson.M{"status": "active"},
+				bson.M{"$or": []interface{}{bson.M{"age": bson.M{"$lt": 18}}, bson.M{"guardian": bson.M{"$ne": nil}}}},
+			}}},
+		{"Dotted field", dbx.Eq("address.city", "Paris"), bson.M{"address.city": "Paris"}},
+	}
+
+	for _, c := range cases {
+		c := c
+		Convey(c.name, t, func() {
+			ShouldEqual(c.bson, filterToBson(c.filter))
+		})
+	}
+}
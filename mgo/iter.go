@@ -1,5 +1,10 @@
 package mgo
 
+import (
+	"github.com/jucardi/go-db/entity"
+	"gopkg.in/mgo.v2"
+)
+
 type IIter interface {
 	// Err returns nil if no errors happened during iteration, or the actual
 	// error otherwise.
@@ -38,3 +43,52 @@ type IIter interface {
 	// See Iter as an elegant replacement.
 	For(result interface{}, f func() error) (err error)
 }
+
+// iter wraps *mgo.Iter to invoke the AfterFound hook on the retrieved documents. If the hook fails, the iteration
+// stops and the error is returned by Err and Close.
+type iter struct {
+	*mgo.Iter
+	err error
+}
+
+func (i *iter) Err() error {
+	if i.err != nil {
+		return i.err
+	}
+	return i.Iter.Err()
+}
+
+func (i *iter) Close() error {
+	err := i.Iter.Close()
+	if i.err != nil {
+		return i.err
+	}
+	return err
+}
+
+func (i *iter) Next(result interface{}) bool {
+	if i.err != nil || !i.Iter.Next(result) {
+		return false
+	}
+	if err := entity.InvokeResult(entity.MethodAfterFound, result); err != nil {
+		i.err = err
+		return false
+	}
+	return true
+}
+
+func (i *iter) All(result interface{}) error {
+	if err := i.Iter.All(result); err != nil {
+		return err
+	}
+	return entity.InvokeResult(entity.MethodAfterFound, result)
+}
+
+func (i *iter) For(result interface{}, f func() error) error {
+	for i.Next(result) {
+		if err := f(); err != nil {
+			return err
+		}
+	}
+	return i.Err()
+}
@@ -3,6 +3,7 @@ package mgo
 import (
 	"github.com/jucardi/go-db"
 	"github.com/jucardi/go-db/common"
+	"github.com/jucardi/go-db/entity"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 	"time"
@@ -79,10 +80,40 @@ type query struct {
 	logReplay bool
 	hints     [][]string
 	comments  []string
+	guard     *contextGuard
+	callbacks *common.CallbacksManager
 }
 
 func (q *query) Count() (n int, err error) {
-	return q.prepare().Count()
+	scope := q.newScope(dbx.OpQuery)
+	scope.Result = &n
+	err = q.run(scope, func() (err error) {
+		n, err = q.prepare().Count()
+		return
+	})
+	return
+}
+
+// EstimatedCount returns the amount of documents in the collection from the collection stats. Not available if the
+// query has conditions.
+func (q *query) EstimatedCount() (n int, ok bool, err error) {
+	if q.HasConditions() {
+		return 0, false, nil
+	}
+
+	scope := q.newScope(dbx.OpQuery)
+	scope.Result = &n
+	err = q.run(scope, func() error {
+		var stats struct {
+			Count int `bson:"count"`
+		}
+		if err := q.col.Database.Run(bson.D{{Name: "collStats", Value: q.col.Name}}, &stats); err != nil {
+			return err
+		}
+		n, ok = stats.Count, true
+		return nil
+	})
+	return
 }
 
 func (q *query) First(result interface{}) error {
@@ -90,30 +121,64 @@ func (q *query) First(result interface{}) error {
 }
 
 func (q *query) One(result interface{}) error {
-	return q.prepare().One(result)
+	scope := q.newScope(dbx.OpQuery)
+	scope.Result = result
+	return q.run(scope, func() error {
+		if err := q.prepare().One(result); err != nil {
+			return err
+		}
+		return entity.InvokeResult(entity.MethodAfterFound, result)
+	})
 }
 
 func (q *query) Last(result interface{}) error {
-	count, err := q.Count()
-	if err != nil {
-		return err
-	}
-	return q.prepare().Skip(count - 1).One(result)
+	scope := q.newScope(dbx.OpQuery)
+	scope.Result = result
+	return q.run(scope, func() error {
+		count, err := q.prepare().Count()
+		if err != nil {
+			return err
+		}
+		if err := q.prepare().Skip(count - 1).One(result); err != nil {
+			return err
+		}
+		return entity.InvokeResult(entity.MethodAfterFound, result)
+	})
 }
 
 func (q *query) All(result interface{}) error {
-	return q.prepare().All(result)
+	scope := q.newScope(dbx.OpQuery)
+	scope.Result = result
+	return q.run(scope, func() error {
+		if err := q.prepare().All(result); err != nil {
+			return err
+		}
+		return entity.InvokeResult(entity.MethodAfterFound, result)
+	})
 }
 
 func (q *query) Distinct(key string, result interface{}) error {
-	return q.prepare().Distinct(key, result)
+	scope := q.newScope(dbx.OpQuery)
+	scope.Result = result
+	return q.run(scope, func() error {
+		return q.prepare().Distinct(key, result)
+	})
 }
 
 func (q *query) Update(update interface{}) error {
-	_, err := q.prepare().Apply(mgo.Change{
-		Update: update,
-	}, nil)
-	return err
+	if err := entity.Invoke(entity.MethodBeforeUpdate, update); err != nil {
+		return err
+	}
+	scope := q.newScope(dbx.OpUpdate)
+	scope.Documents = []interface{}{update}
+	return q.run(scope, func() error {
+		if _, err := q.prepare().Apply(mgo.Change{
+			Update: update,
+		}, nil); err != nil {
+			return err
+		}
+		return entity.Invoke(entity.MethodAfterUpdate, update)
+	})
 }
 
 func (q *query) Delete() error {
@@ -121,10 +186,12 @@ func (q *query) Delete() error {
 }
 
 func (q *query) Remove() error {
-	_, err := q.prepare().Apply(mgo.Change{
-		Remove: true,
-	}, nil)
-	return err
+	return q.run(q.newScope(dbx.OpDelete), func() error {
+		_, err := q.prepare().Apply(mgo.Change{
+			Remove: true,
+		}, nil)
+		return err
+	})
 }
 
 func (q *query) Explain(result interface{}) error {
@@ -132,11 +199,11 @@ func (q *query) Explain(result interface{}) error {
 }
 
 func (q *query) Iter() IIter {
-	return q.prepare().Iter()
+	return &iter{Iter: q.prepare().Iter()}
 }
 
 func (q *query) Tail(timeout time.Duration) IIter {
-	return q.prepare().Tail(timeout)
+	return &iter{Iter: q.prepare().Tail(timeout)}
 }
 
 func (q *query) MapReduce(job *MapReduce, result interface{}) (*MapReduceInfo, error) {
@@ -193,6 +260,20 @@ func (q *query) Q() *mgo.Query {
 	return q.qry
 }
 
+// run runs the provided action invoking the registered callbacks.
+func (q *query) run(scope *dbx.Scope, action func() error) error {
+	return q.callbacks.Run(scope, q.guard.wrap(action))
+}
+
+func (q *query) newScope(op dbx.Operation) *dbx.Scope {
+	return &dbx.Scope{
+		Context:   q.Ctx,
+		Operation: op,
+		Repo:      q.col.Name,
+		Condition: q.makeQuery(),
+	}
+}
+
 func (q *query) prepare() *mgo.Query {
 	q.qry = q.col.Find(q.makeQuery())
 	if q.LimitVal != nil {
@@ -218,6 +299,8 @@ func (q *query) prepare() *mgo.Query {
 	}
 	if q.maxTime != nil {
 		q.qry = q.qry.SetMaxTime(*q.maxTime)
+	} else if deadline, ok := q.deadline(); ok {
+		q.qry = q.qry.SetMaxTime(time.Until(deadline))
 	}
 	if q.snapshot {
 		q.qry = q.qry.Snapshot()
@@ -235,16 +318,26 @@ func (q *query) prepare() *mgo.Query {
 	return q.qry
 }
 
+func (q *query) deadline() (time.Time, bool) {
+	if q.Ctx == nil {
+		return time.Time{}, false
+	}
+	return q.Ctx.Deadline()
+}
+
 func (q *query) makeQuery() interface{} {
 	var blocks []interface{}
 
 	for _, block := range q.Queries {
 		var current []interface{}
 		for _, cond := range block {
+			if cond.Query == nil {
+				continue
+			}
 			if cond.Negation {
-				current = append(current, bson.M{"$not": cond.Query})
+				current = append(current, bson.M{"$nor": []interface{}{toBson(cond.Query)}})
 			} else {
-				current = append(current, cond.Query)
+				current = append(current, toBson(cond.Query))
 			}
 		}
 
@@ -263,16 +356,19 @@ func (q *query) makeQuery() interface{} {
 	case 1:
 		return blocks[0]
 	default:
-		return bson.M{"or": blocks}
+		return bson.M{"$or": blocks}
 	}
 }
 
-func newQuery(col *mgo.Collection, qry interface{}, negated bool) IQuery {
+func newQuery(c *collection, qry interface{}, negated bool) IQuery {
 	ret := &query{
-		col: col,
+		col:       c.C(),
+		guard:     c.guard,
+		callbacks: c.callbacks,
 	}
 	ret.AbstractQuery = &common.AbstractQuery{
-		Q: ret,
+		Q:   ret,
+		Ctx: c.ctx,
 	}
 	if negated {
 		ret.Not(qry)
@@ -0,0 +1,108 @@
+package pages
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a cursor token is malformed, was signed with a different key or was created for a
+// different sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+var (
+	cursorKey []byte
+	keyMutex  sync.RWMutex
+)
+
+func init() {
+	gob.Register(time.Time{})
+
+	cursorKey = make([]byte, 32)
+	if _, err := rand.Read(cursorKey); err != nil {
+		panic(err)
+	}
+}
+
+// Cursor encapsulates the information required to request a subset of a result set relative to the position of a
+// previous subset (keyset pagination), instead of by page number. Unlike `Page`, it does not skip records, so the
+// performance does not degrade with the position in the result set, and records are not repeated or skipped when
+// the data changes between requests.
+type Cursor struct {
+	Token string    `json:"cursor,omitempty"` // The next or previous cursor of a previous result. Empty for the first page.
+	Size  int       `json:"size"`             // The page size of the subset.
+	Sort  []string  `json:"sort,omitempty"`   // The fields to use for a sorting algorithm. Use '-' at the beginning for reverse order. Eg "-name".
+	Count CountMode `json:"count,omitempty"`  // How the total amount of items is obtained, see `CountMode`.
+}
+
+// CursorPosition is the information encoded in a cursor token
+type CursorPosition struct {
+	// Values are the values of the sort fields of the last item returned, or the first if Backward is true
+	Values []interface{}
+
+	// Sort are the sort fields the values correspond to, including the field appended to make the order unique
+	Sort []string
+
+	// Backward indicates whether the cursor points to the items before the position
+	Backward bool
+}
+
+// SetCursorKey sets the key used to sign the cursor tokens. By default, a random key is generated on startup, which
+// means tokens are only valid within the same process. Services running multiple instances must set the same key in
+// every instance.
+func SetCursorKey(key []byte) {
+	keyMutex.Lock()
+	defer keyMutex.Unlock()
+	cursorKey = append([]byte{}, key...)
+}
+
+// EncodeCursor creates an opaque signed token for the provided position. The values are encoded with encoding/gob, so
+// values of custom types must be registered with `gob.Register`.
+func EncodeCursor(pos *CursorPosition) (string, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(pos); err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(buf.Bytes())
+	return payload + "." + base64.RawURLEncoding.EncodeToString(sign(payload)), nil
+}
+
+// DecodeCursor validates the signature of a token created by `EncodeCursor` and returns the position it encodes.
+func DecodeCursor(token string) (*CursorPosition, error) {
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	payload := token[:i]
+	signature, err := base64.RawURLEncoding.DecodeString(token[i+1:])
+	if err != nil || !hmac.Equal(signature, sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	ret := &CursorPosition{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(ret); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return ret, nil
+}
+
+func sign(payload string) []byte {
+	keyMutex.RLock()
+	defer keyMutex.RUnlock()
+	mac := hmac.New(sha256.New, cursorKey)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
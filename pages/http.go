@@ -0,0 +1,196 @@
+package pages
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultSize is the page size used by `FromRequest` when none is requested and `RequestOptions.DefaultSize` is
+	// not set.
+	DefaultSize = 20
+
+	// DefaultMaxSize is the maximum page size used by `FromRequest` when `RequestOptions.MaxSize` is not set.
+	DefaultMaxSize = 100
+)
+
+// ErrInvalidRequest is wrapped by the errors returned by `FromRequest` and `CursorFromRequest` when the query string
+// contains invalid page parameters, so they can be reported as a bad request.
+var ErrInvalidRequest = errors.New("invalid page request")
+
+// RequestOptions are the options used to create a page from the query string of an HTTP request.
+type RequestOptions struct {
+	// DefaultSize is the page size when the 'size' parameter is not provided. `DefaultSize` if zero.
+	DefaultSize int
+
+	// MaxSize is the maximum page size, larger sizes are reduced to it. `DefaultMaxSize` if zero.
+	MaxSize int
+
+	// SortFields are the fields that can be used in the 'sort' parameter. Requests sorting by any other field are
+	// rejected, since the fields are passed to the database as they are. If empty, sorting is not allowed.
+	SortFields []string
+
+	// DefaultSort are the sort fields used when the 'sort' parameter is not provided.
+	DefaultSort []string
+}
+
+// FromRequest creates a page from the query string of an HTTP request, e.g. '?page=2&size=50&sort=-name'. Multiple sort
+// fields can be provided by repeating the 'sort' parameter or separated by commas. Returns an error wrapping
+// `ErrInvalidRequest` if any of the parameters is invalid.
+func FromRequest(r *http.Request, opts ...*RequestOptions) (*Page, error) {
+	o := requestOptions(opts)
+	query := r.URL.Query()
+
+	page := 1
+	if v := query.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%w, 'page' must be a positive integer", ErrInvalidRequest)
+		}
+		page = n
+	}
+
+	size, err := parseSize(query, o)
+	if err != nil {
+		return nil, err
+	}
+
+	sort, err := parseSort(query, o)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Page{Page: page, Size: size, Sort: sort}, nil
+}
+
+// CursorFromRequest creates a cursor from the query string of an HTTP request, e.g. '?cursor=abc&size=50&sort=-name'.
+// See `FromRequest`.
+func CursorFromRequest(r *http.Request, opts ...*RequestOptions) (*Cursor, error) {
+	o := requestOptions(opts)
+	query := r.URL.Query()
+
+	size, err := parseSize(query, o)
+	if err != nil {
+		return nil, err
+	}
+
+	sort, err := parseSort(query, o)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cursor{Token: query.Get("cursor"), Size: size, Sort: sort}, nil
+}
+
+// WriteHeaders sets the 'X-Total-Count' header and the RFC 5988 'Link' header of a paginated response, with links to
+// the first, previous, next and last pages. The links are created from the URL of the request, replacing the 'page'
+// parameter, or the 'cursor' parameter if the result was obtained with a cursor. The last page link is only set if the
+// totals are exact, and the total count header is not set if the items were not counted. For example:
+//
+//	pages.WriteHeaders(w, r, result.PaginatedBase)
+func WriteHeaders(w http.ResponseWriter, r *http.Request, p *PaginatedBase) {
+	if p == nil {
+		return
+	}
+	if p.CountMode != CountNone {
+		w.Header().Set("X-Total-Count", strconv.Itoa(p.TotalCount))
+	}
+
+	var links []string
+	link := func(rel, param, value string) {
+		u := *r.URL
+		query := u.Query()
+		if value == "" {
+			query.Del(param)
+		} else {
+			query.Set(param, value)
+		}
+		u.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.String(), rel))
+	}
+
+	if p.NextCursor != "" || p.PrevCursor != "" {
+		link("first", "cursor", "")
+		if p.PrevCursor != "" {
+			link("prev", "cursor", p.PrevCursor)
+		}
+		if p.NextCursor != "" {
+			link("next", "cursor", p.NextCursor)
+		}
+	} else if p.Page > 0 {
+		link("first", "page", "1")
+		if p.Page > 1 {
+			link("prev", "page", strconv.Itoa(p.Page-1))
+		}
+		exact := p.CountMode == CountExact
+		if p.HasNext || exact && p.Page < p.TotalPages {
+			link("next", "page", strconv.Itoa(p.Page+1))
+		}
+		if exact && p.TotalPages > 0 {
+			link("last", "page", strconv.Itoa(p.TotalPages))
+		}
+	}
+
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+func requestOptions(opts []*RequestOptions) *RequestOptions {
+	ret := &RequestOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		*ret = *opts[0]
+	}
+	if ret.DefaultSize <= 0 {
+		ret.DefaultSize = DefaultSize
+	}
+	if ret.MaxSize <= 0 {
+		ret.MaxSize = DefaultMaxSize
+	}
+	return ret
+}
+
+func parseSize(query url.Values, o *RequestOptions) (int, error) {
+	size := o.DefaultSize
+	if v := query.Get("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("%w, 'size' must be a positive integer", ErrInvalidRequest)
+		}
+		size = n
+	}
+	if size > o.MaxSize {
+		size = o.MaxSize
+	}
+	return size, nil
+}
+
+func parseSort(query url.Values, o *RequestOptions) ([]string, error) {
+	allowed := map[string]bool{}
+	for _, f := range o.SortFields {
+		allowed[f] = true
+	}
+
+	var ret []string
+	for _, v := range query["sort"] {
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			if !allowed[strings.TrimPrefix(field, "-")] {
+				return nil, fmt.Errorf("%w, sorting by '%s' is not allowed", ErrInvalidRequest, field)
+			}
+			ret = append(ret, field)
+		}
+	}
+
+	if len(ret) == 0 {
+		return append([]string{}, o.DefaultSort...), nil
+	}
+	return ret, nil
+}
@@ -0,0 +1,61 @@
+package pages
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/jucardi/go-testx/testx"
+)
+
+func TestFromRequest(t *testing.T) {
+	opts := &RequestOptions{MaxSize: 50, SortFields: []string{"name", "age"}}
+
+	Convey("Parses the page parameters", t, func() {
+		p, err := FromRequest(httptest.NewRequest("GET", "/users?page=2&size=500&sort=-name,age", nil), opts)
+		ShouldBeNil(err)
+		ShouldEqual(2, p.Page)
+		ShouldEqual(50, p.Size)
+		ShouldEqual([]string{"-name", "age"}, p.Sort)
+
+		p, err = FromRequest(httptest.NewRequest("GET", "/users", nil))
+		ShouldBeNil(err)
+		ShouldEqual(1, p.Page)
+		ShouldEqual(DefaultSize, p.Size)
+	})
+	Convey("Rejects invalid parameters and unknown sort fields", t, func() {
+		_, err := FromRequest(httptest.NewRequest("GET", "/users?page=0", nil), opts)
+		ShouldBeTrue(errors.Is(err, ErrInvalidRequest))
+
+		_, err = FromRequest(httptest.NewRequest("GET", "/users?sort=password", nil), opts)
+		ShouldBeTrue(errors.Is(err, ErrInvalidRequest))
+	})
+}
+
+func TestWriteHeaders(t *testing.T) {
+	Convey("Writes the total count and the page links", t, func() {
+		r := httptest.NewRequest("GET", "/users?page=2&size=10", nil)
+		w := httptest.NewRecorder()
+		WriteHeaders(w, r, &PaginatedBase{TotalCount: 25, TotalPages: 3, Size: 10, Page: 2})
+
+		ShouldEqual("25", w.Header().Get("X-Total-Count"))
+		ShouldEqual(`</users?page=1&size=10>; rel="first", </users?page=1&size=10>; rel="prev", `+
+			`</users?page=3&size=10>; rel="next", </users?page=3&size=10>; rel="last"`, w.Header().Get("Link"))
+	})
+	Convey("Omits the totals and the last page when the items were not counted", t, func() {
+		r := httptest.NewRequest("GET", "/users?page=2&size=10", nil)
+		w := httptest.NewRecorder()
+		WriteHeaders(w, r, &PaginatedBase{Size: 10, Page: 2, CountMode: CountNone, HasNext: true})
+
+		ShouldEqual("", w.Header().Get("X-Total-Count"))
+		ShouldEqual(`</users?page=1&size=10>; rel="first", </users?page=1&size=10>; rel="prev", `+
+			`</users?page=3&size=10>; rel="next"`, w.Header().Get("Link"))
+	})
+	Convey("Uses the cursors when available", t, func() {
+		r := httptest.NewRequest("GET", "/users?size=10", nil)
+		w := httptest.NewRecorder()
+		WriteHeaders(w, r, &PaginatedBase{TotalCount: 25, Size: 10, NextCursor: "abc"})
+
+		ShouldEqual(`</users?size=10>; rel="first", </users?cursor=abc&size=10>; rel="next"`, w.Header().Get("Link"))
+	})
+}
@@ -0,0 +1,49 @@
+package pages
+
+import "fmt"
+
+// PaginatedOf is the typed version of Paginated, it serializes to the same JSON, so it can be used both to return
+// paginated results and to deserialize them, without declaring a struct for each item type. For example:
+//
+//	paginated := &pages.PaginatedOf[*User]{}
+//	err := json.Unmarshal(respBytes, paginated)
+type PaginatedOf[T any] struct {
+	*PaginatedBase
+	Items []T `json:"content"` // The array of items in the result.
+}
+
+// Typed converts a Paginated to its typed version. Returns an error if the items are not of type []T.
+func Typed[T any](p *Paginated) (*PaginatedOf[T], error) {
+	if p == nil {
+		return nil, nil
+	}
+	if p.Items == nil {
+		return &PaginatedOf[T]{PaginatedBase: p.PaginatedBase}, nil
+	}
+	items, ok := p.Items.([]T)
+	if !ok {
+		return nil, fmt.Errorf("unable to convert Paginated, the items are of type %T, not %T", p.Items, items)
+	}
+	return &PaginatedOf[T]{PaginatedBase: p.PaginatedBase, Items: items}, nil
+}
+
+// Untyped converts the typed paginated result to Paginated.
+func (p *PaginatedOf[T]) Untyped() *Paginated {
+	return &Paginated{PaginatedBase: p.PaginatedBase, Items: p.Items}
+}
+
+// Map converts the items of a paginated result using the provided function, preserving the counts, page information
+// and cursors. Useful to convert entities into DTOs before returning them. For example:
+//
+//	dtos := pages.Map(paginated, func(u *User) *UserDTO { return toDTO(u) })
+func Map[T, U any](p *PaginatedOf[T], f func(T) U) *PaginatedOf[U] {
+	ret := &PaginatedOf[U]{Items: make([]U, len(p.Items))}
+	if p.PaginatedBase != nil {
+		base := *p.PaginatedBase
+		ret.PaginatedBase = &base
+	}
+	for i, item := range p.Items {
+		ret.Items[i] = f(item)
+	}
+	return ret
+}
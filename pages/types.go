@@ -6,11 +6,29 @@ import (
 	"reflect"
 )
 
+// CountMode indicates how the total amount of items of a paginated result is obtained
+type CountMode string
+
+const (
+	// CountExact counts the items that match the query. It is the default mode.
+	CountExact CountMode = ""
+
+	// CountNone does not count the items, the totals of the result are zero. Whether there is a next page is determined
+	// by fetching one more item than the page size.
+	CountNone CountMode = "none"
+
+	// CountEstimated uses an estimation of the amount of items in the repository, obtained from the database metadata,
+	// which is much faster than counting on large repositories. The exact count is used if the query has conditions or
+	// the provider does not support estimations.
+	CountEstimated CountMode = "estimated"
+)
+
 // Page encapsulates the essential information required to request a subset of a result set.
 type Page struct {
-	Page int      `json:"page"`           // Page number to be requested.
-	Size int      `json:"size"`           // The page size of the subset.
-	Sort []string `json:"sort,omitempty"` // The fields to use for a sorting algorithm. Use '-' at the beginning for reverse order. Eg "-name".
+	Page  int       `json:"page"`            // Page number to be requested.
+	Size  int       `json:"size"`            // The page size of the subset.
+	Sort  []string  `json:"sort,omitempty"`  // The fields to use for a sorting algorithm. Use '-' at the beginning for reverse order. Eg "-name".
+	Count CountMode `json:"count,omitempty"` // How the total amount of items is obtained, see `CountMode`.
 }
 
 // Paginated result containing a subset of the result set. JSON keys were done to match the names used by Ten-X Java commons library.
@@ -20,7 +38,8 @@ type Paginated struct {
 }
 
 // PaginatedBase contains the base fields for the paginated wrapper. It was separated from Paginated so it can easily
-// be used to created a deserialization struct when the array type is know. For example:
+// be used to created a deserialization struct when the array type is know, although `PaginatedOf` can be used
+// instead. For example:
 //
 //	type PaginatedUsers struct {
 //		*PaginatedBase
@@ -33,6 +52,12 @@ type PaginatedBase struct {
 	TotalCount int `json:"total_count"`  // The total amount of items in the query.
 	Size       int `json:"size"`         // The page size
 	Page       int `json:"current_page"` // The page number this subset represents.
+
+	CountMode CountMode `json:"count_mode,omitempty"` // How the totals were obtained if they are not exact, see `CountMode`. Empty if the totals are exact.
+	HasNext   bool      `json:"has_next,omitempty"`   // Whether there are items after this subset. Only set if the totals are not exact, otherwise it is given by TotalPages.
+
+	NextCursor string `json:"next_cursor,omitempty"` // The cursor to request the next subset, when paginating with a Cursor. Empty if this is the last subset.
+	PrevCursor string `json:"prev_cursor,omitempty"` // The cursor to request the previous subset, when paginating with a Cursor. Empty if this is the first subset.
 }
 
 // CreatePaginated creates the paginated object based on the given page and result.
@@ -51,14 +76,19 @@ func CreatePaginated(p *Page, array interface{}, count ...int) (*Paginated, erro
 	}
 
 	if p == nil {
-		p = &Page{1, l, nil}
+		p = &Page{Page: 1, Size: l}
+	}
+
+	totalPages := 0
+	if p.Size > 0 {
+		totalPages = int(math.Ceil(float64(c) / float64(p.Size)))
 	}
 
 	return &Paginated{
 		Items: arr.Interface(),
 		PaginatedBase: &PaginatedBase{
 			ItemsCount: l,
-			TotalPages: int(math.Ceil(float64(c) / float64(p.Size))),
+			TotalPages: totalPages,
 			TotalCount: c,
 			Size:       p.Size,
 			Page:       p.Page,
@@ -1,6 +1,10 @@
 package dbx
 
-import "github.com/jucardi/go-db/pages"
+import (
+	"context"
+
+	"github.com/jucardi/go-db/pages"
+)
 
 // IQuery is an interface which matches the contract for the `query` struct in `gopkg.in/mgo.v2` package. The function documentation has been narrowed from the original
 // in `gopkg.in/mgo.v2`. For additional documentation, please refer to the `mgo.Collection` in the `gopkg.in/mgo.v2` package.
@@ -8,6 +12,11 @@ type IQuery interface {
 	// Set of extension functions that are not present in the original `mgo` package are defined in the following interface(s):
 	IQueryPageExtension
 
+	// WithContext binds the query to the provided context. The query is aborted if the context is done before it is executed.
+	// SQL queries are not interruptible: gorm v1 does not support contexts, so the context is only checked before each
+	// statement is sent.
+	WithContext(ctx context.Context) IQuery
+
 	// Limit restricts the maximum number of records retrieved to n, and also changes the batch size to the same value.
 	Limit(n int) IQuery
 
@@ -23,11 +32,13 @@ type IQuery interface {
 	Select(query interface{}, args ...interface{}) IQuery
 
 	// Where adds an additional condition to match records in the query. It is associated with the previous queries with an AND prepares a query script using the provided condition object to match any records that meet the provided condition.
-	// Accepts `map`, `struct`. For SQL, it also accepts `string` as conditions, refer http://jinzhu.github.io/gorm/crud.html#query
+	// Accepts `*dbx.Filter` (see `dbx.Eq`, `dbx.And`, etc.) which is portable across providers, `map` and `struct`.
+	// For SQL, it also accepts `string` conditions and primary key values, refer http://jinzhu.github.io/gorm/crud.html#query
 	Where(condition interface{}, args ...interface{}) IQuery
 
 	// Not prepares a query script using the provided condition object to match any records that do not meet the provided condition.
-	// Accepts `map`, `struct`. For SQL, it also accepts `string` as conditions, refer http://jinzhu.github.io/gorm/crud.html#query
+	// Accepts `*dbx.Filter` (see `dbx.Eq`, `dbx.And`, etc.) which is portable across providers, `map` and `struct`.
+	// For SQL, it also accepts `string` conditions and primary key values, refer http://jinzhu.github.io/gorm/crud.html#query
 	Not(condition interface{}, args ...interface{}) IQuery
 
 	// Or indicates that any following queries in the chain will be OR'ed with the previous queries
@@ -52,12 +63,18 @@ type IQuery interface {
 	Distinct(key string, result interface{}) error
 
 	// Update update attributes with callbacks, refer: https://jinzhu.github.io/gorm/crud.html#update
+	// The MongoDB and in-memory providers update only the first record resulting from executing the query, in sort order,
+	// and return a not found error if there is none. SQL providers update all of them.
 	Update(update interface{}) error
 
 	// Delete deletes the records resulting from executing the query. Alias 'Remove'
+	// The MongoDB and in-memory providers delete only the first record resulting from executing the query, in sort order,
+	// and return a not found error if there is none. SQL providers delete all of them. Use `IRepository.Delete` to delete
+	// all the matching records with any provider.
 	Delete() error
 
 	// Remove deletes the records resulting from executing the query. Alias 'Delete'
+	// See `Delete` for the differences between providers.
 	Remove() error
 }
 
@@ -68,4 +85,17 @@ type IQueryPageExtension interface {
 
 	// WrapPage attempts to obtain the items in the requested page and wraps the result in *pages.Paginated
 	WrapPage(result interface{}, p ...*pages.Page) (*pages.Paginated, error)
+
+	// WrapCursor attempts to obtain the items in the subset requested by the cursor using keyset pagination, and wraps
+	// the result in *pages.Paginated along with the cursors to request the next and previous subsets. The sort fields
+	// must be provided in the cursor rather than with `Sort`.
+	WrapCursor(result interface{}, c *pages.Cursor) (*pages.Paginated, error)
+}
+
+// IQueryEstimatedCount is implemented by the queries of providers able to estimate the amount of records in a
+// repository from the database metadata, used by the `pages.CountEstimated` count mode.
+type IQueryEstimatedCount interface {
+	// EstimatedCount returns an estimation of the amount of records in the repository. Returns false if an estimation
+	// is not available, or if the query has conditions, since the estimation does not take them into account.
+	EstimatedCount() (int, bool, error)
 }
\ No newline at end of file
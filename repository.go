@@ -1,5 +1,7 @@
 package dbx
 
+import "context"
+
 // IRepository represents a repository of records (table for SQL, collection for MongoDB)
 type IRepository interface {
 	// Insert inserts one or more records in the respective repository.
@@ -8,12 +10,18 @@ type IRepository interface {
 	// Drop drops the repository (table if SQL, collection if MongoDB)
 	Drop() error
 
+	// WithContext returns a copy of the repository bound to the provided context. Operations executed through the
+	// returned repository, or the queries obtained from it, are aborted when the context is done.
+	WithContext(ctx context.Context) IRepository
+
 	// Where prepares a query script using the provided condition object to match any records that meet the provided condition.
-	// Accepts `map`, `struct`. For SQL, it also accepts `string` as conditions, refer http://jinzhu.github.io/gorm/crud.html#query/
+	// Accepts `*dbx.Filter` (see `dbx.Eq`, `dbx.And`, etc.) which is portable across providers, `map` and `struct`.
+	// For SQL, it also accepts `string` conditions and primary key values, refer http://jinzhu.github.io/gorm/crud.html#query
 	Where(condition interface{}, args ...interface{}) IQuery
 
 	// Not prepares a query script using the provided condition object to match any records that do not meet the provided condition.
-	// Accepts `map`, `struct`. For SQL, it also accepts `string` as conditions, refer http://jinzhu.github.io/gorm/crud.html#query
+	// Accepts `*dbx.Filter` (see `dbx.Eq`, `dbx.And`, etc.) which is portable across providers, `map` and `struct`.
+	// For SQL, it also accepts `string` conditions and primary key values, refer http://jinzhu.github.io/gorm/crud.html#query
 	Not(condition interface{}, args ...interface{}) IQuery
 
 	// AddIndex adds an index for the provided fields (columns for SQL, keys for MongoDB)
@@ -1,6 +1,8 @@
 package sql
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"github.com/jinzhu/gorm"
 	"github.com/jucardi/go-db"
@@ -8,6 +10,8 @@ import (
 	"github.com/jucardi/go-db/logger"
 	"github.com/jucardi/go-logger-lib/log"
 	"github.com/jucardi/go-streams/streams"
+	"io/fs"
+	"strings"
 )
 
 type IDatabase interface {
@@ -60,14 +64,18 @@ type IDatabase interface {
 
 type database struct {
 	*gorm.DB
-	isClone  bool
-	executor dbx.ScriptExecutor
+	isClone   bool
+	inTx      bool
+	ctx       context.Context
+	executor  dbx.ScriptExecutor
+	callbacks *common.CallbacksManager
 }
 
 func FromDB(db *gorm.DB, isClone bool) IDatabase {
 	return &database{
-		DB:      db,
-		isClone: isClone,
+		DB:        db,
+		isClone:   isClone,
+		callbacks: common.NewCallbacksManager(),
 	}
 }
 
@@ -76,7 +84,11 @@ func (db *database) Db() *gorm.DB {
 }
 
 func (db *database) Clone() dbx.IDatabase {
-	return FromDB(db.DB.New(), true)
+	ret := db.derive(db.DB.New())
+	ret.isClone = true
+	ret.inTx = false
+	ret.executor = nil
+	return ret
 }
 
 func (db *database) Close() {
@@ -89,8 +101,61 @@ func (db *database) Close() {
 	}
 }
 
+// WithContext returns a copy of the database bound to the provided context. Transactions started from the returned
+// database are bound to the context, as well as the scripts executed by `Exec` and `Run`. Since gorm does not support
+// contexts for any other operation, those are aborted only if the context is done before they are executed.
+func (db *database) WithContext(ctx context.Context) dbx.IDatabase {
+	ret := db.derive(db.DB)
+	ret.ctx = ctx
+	return ret
+}
+
+// Begin begins a transaction. Repositories obtained through the returned transaction participate in it.
+func (db *database) Begin() (dbx.ITransaction, error) {
+	if err := db.checkContext(); err != nil {
+		return nil, err
+	}
+	var tx *gorm.DB
+	if db.ctx != nil {
+		tx = db.DB.BeginTx(db.ctx, &sql.TxOptions{})
+	} else {
+		tx = db.DB.Begin()
+	}
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	ret := db.derive(tx)
+	ret.isClone = true
+	ret.inTx = true
+	return ret, nil
+}
+
+func (db *database) Commit() error {
+	if !db.inTx {
+		return dbx.ErrNotInTransaction
+	}
+	return db.DB.Commit().Error
+}
+
+func (db *database) Rollback() error {
+	if !db.inTx {
+		return dbx.ErrNotInTransaction
+	}
+	return db.DB.Rollback().Error
+}
+
+func (db *database) WithTransaction(fn func(tx dbx.IDatabase) error) error {
+	return common.TransactionHandler(db, fn)
+}
+
+// AtomicTransactions returns true, changes made in a transaction that is rolled back are reverted. Note that some
+// statements cause an implicit commit in some databases, e.g. DDL statements in MySQL.
+func (db *database) AtomicTransactions() bool {
+	return true
+}
+
 func (db *database) Callbacks() dbx.ICallbacksManager {
-	panic("implement me")
+	return db.callbacks
 }
 
 func (db *database) SetLogger(l log.ILogger) {
@@ -108,32 +173,113 @@ func (db *database) Repo(name string) dbx.IRepository {
 	return db.Table(name)
 }
 
+// Exec executes the provided script and maps the returned rows into the result, which may be a pointer to a struct, a
+// slice of structs, a map[string]interface{}, a slice of maps or a scalar. For scripts that do not return rows, pass a
+// *ExecResult to obtain the rows affected and the last insert id, or nil to ignore the result.
 func (db *database) Exec(script string, result interface{}) error {
-	// TODO: map result
-	return db.DB.Exec(script).Error
+	if err := db.checkContext(); err != nil {
+		return err
+	}
+	switch r := result.(type) {
+	case nil:
+		return db.exec(script)
+	case *ExecResult:
+		return db.execResult(script, r)
+	}
+	return db.query(script, result)
 }
 
 func (db *database) Run(script string) error {
+	if err := db.checkContext(); err != nil {
+		return err
+	}
 	if db.executor != nil {
 		return db.executor(script)
 	}
-	return db.DB.Exec(script).Error
+
+	statements, err := SplitScript(script, db.DB.Dialect().GetName())
+	if err != nil {
+		return &dbx.DbError{
+			Message: fmt.Sprintf("unable to parse the script, %s", err.Error()),
+			Code:    dbx.ErrDbOperation,
+		}
+	}
+	for _, statement := range statements {
+		if err := db.exec(statement.Text); err != nil {
+			return &dbx.DbError{
+				Message: fmt.Sprintf("statement at line %d failed, %s. Statement: %s", statement.Line, err.Error(), summarize(statement.Text)),
+				Code:    dbx.ErrDbOperation,
+			}
+		}
+	}
+	return nil
 }
 
+// summarize returns the first line of a statement, abbreviated for error messages
+func summarize(statement string) string {
+	const max = 80
+	ret := statement
+	if i := strings.IndexByte(ret, '\n'); i >= 0 {
+		ret = ret[:i] + " ..."
+	}
+	if len(ret) > max {
+		ret = ret[:max] + " ..."
+	}
+	return ret
+}
+
+// HasRepo checks whether the table exists. For PostgreSQL, the name may be qualified by schema ('schema.table').
 func (db *database) HasRepo(name string) bool {
+	if db.DB.Dialect().GetName() == postgresDialect {
+		return postgresHasTable(db.DB, name)
+	}
 	return db.DB.HasTable(name)
 }
 
+// CreateRepo creates the table using the provided models. For PostgreSQL, the schema of a name qualified by schema
+// ('schema.table') is created if it does not exist.
 func (db *database) CreateRepo(name string, models ...interface{}) error {
+	if db.DB.Dialect().GetName() == postgresDialect {
+		if err := postgresEnsureSchema(db.DB, name); err != nil {
+			return err
+		}
+	}
 	return db.DB.Table(name).CreateTable(models...).Error
 }
 
+// UpdateRepo adds to an existing table the missing columns and indexes of the provided models. Existing columns are not
+// modified.
+func (db *database) UpdateRepo(name string, models ...interface{}) error {
+	return db.DB.Table(name).AutoMigrate(models...).Error
+}
+
 func (db *database) Migrate(dataDir string, failOnOrderMismatch ...bool) error {
 	fail := true
 	if len(failOnOrderMismatch) > 0 {
 		fail = failOnOrderMismatch[0]
 	}
-	return common.Migrate(dataDir, db, fail)
+	migrator := &common.Migrator{
+		Db:                  db,
+		DataDir:             dataDir,
+		FailOnOrderMismatch: fail,
+		Context:             db.ctx,
+	}
+	return migrator.Migrate()
+}
+
+func (db *database) MigrateFS(fsys fs.FS, dir string, failOnOrderMismatch ...bool) error {
+	fail := true
+	if len(failOnOrderMismatch) > 0 {
+		fail = failOnOrderMismatch[0]
+	}
+	migrator := &common.Migrator{
+		Db:                  db,
+		FS:                  fsys,
+		DataDir:             dir,
+		FailOnOrderMismatch: fail,
+		Context:             db.ctx,
+	}
+	return migrator.Migrate()
 }
 
 func (db *database) SetScriptExecutor(executor dbx.ScriptExecutor) {
@@ -155,15 +301,15 @@ func (db *database) Scopes(funcs ...func(IDatabase) IDatabase) IDatabase {
 }
 
 func (db *database) Unscoped() IDatabase {
-	return FromDB(db.DB.Unscoped(), false)
+	return db.derive(db.DB.Unscoped())
 }
 
 func (db *database) Model(value interface{}) ITable {
-	return newTable(db.DB, value)
+	return newTable(db.DB, value, db.callbacks, db.ctx)
 }
 
 func (db *database) Table(name string) ITable {
-	return newTable(db.DB, name)
+	return newTable(db.DB, name, db.callbacks, db.ctx)
 }
 
 func (db *database) T(name string) ITable {
@@ -181,3 +327,37 @@ func (db *database) AddForeignKey(field string, dest string, onDelete string, on
 func (db *database) RemoveForeignKey(field string, dest string) error {
 	return db.DB.RemoveForeignKey(field, dest).Error
 }
+
+// derive returns a shallow copy of the database using the provided *gorm.DB
+func (db *database) derive(gdb *gorm.DB) *database {
+	ret := *db
+	ret.DB = gdb
+	return &ret
+}
+
+func (db *database) checkContext() error {
+	if db.ctx != nil {
+		return db.ctx.Err()
+	}
+	return nil
+}
+
+// exec executes the given script, using the bound context if any.
+func (db *database) exec(script string) error {
+	if db.ctx == nil {
+		return db.DB.Exec(script).Error
+	}
+	if execer, ok := db.DB.CommonDB().(execContext); ok {
+		_, err := execer.ExecContext(db.ctx, script)
+		return err
+	}
+	if err := db.checkContext(); err != nil {
+		return err
+	}
+	return db.DB.Exec(script).Error
+}
+
+// execContext is implemented by *sql.DB and *sql.Tx
+type execContext interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
@@ -0,0 +1,198 @@
+package sql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/common"
+	"github.com/jucardi/go-db/entity"
+)
+
+const softDeleteColumn = "deleted_at"
+
+// remove deletes the rows matching the query. If the model of the query is known and it implements the BeforeDelete
+// or AfterDelete hooks, the matching rows are loaded first so the hooks can be invoked for each of them, and the
+// deletion is restricted to their primary keys.
+//
+// Models with a 'DeletedAt' field are soft deleted as gorm does, unless the query is unscoped.
+func (q *query) remove() (int64, error) {
+	clause, args, err := buildWhere(q.db, q.Queries)
+	if err != nil {
+		return 0, err
+	}
+
+	records, err := q.loadForHooks()
+	if err != nil {
+		return 0, err
+	}
+
+	paginate := true
+	if records != nil {
+		if len(records) == 0 {
+			return 0, nil
+		}
+		if err := entity.Invoke(entity.MethodBeforeDelete, records...); err != nil {
+			return 0, err
+		}
+		if pkClause, pkArgs, ok := q.primaryKeyClause(records); ok {
+			clause, args, paginate = pkClause, pkArgs, false
+		}
+	}
+
+	script, err := q.deleteScript(clause, paginate)
+	if err != nil {
+		return 0, err
+	}
+
+	result := q.db.Exec(script, args...)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if records != nil {
+		if err := entity.Invoke(entity.MethodAfterDelete, records...); err != nil {
+			return result.RowsAffected, err
+		}
+	}
+	return result.RowsAffected, nil
+}
+
+// deleteScript builds the DELETE statement (or UPDATE for soft deletes) for the given where clause. If 'paginate' is
+// true, the sorting, skip and limit of the query are applied using the means available in the dialect.
+func (q *query) deleteScript(clause string, paginate bool) (string, error) {
+	scope := q.db.NewScope(q.db.Value)
+	table := scope.QuotedTableName()
+
+	var conditions []string
+	if clause != "" {
+		conditions = append(conditions, clause)
+	}
+
+	soft := q.db.Value != nil && scope.HasColumn(softDeleteColumn) && !scope.Search.Unscoped
+	if soft {
+		conditions = append(conditions, fmt.Sprintf("%s.%s IS NULL", table, scope.Quote(softDeleteColumn)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	statement := "DELETE FROM " + table
+	if soft {
+		statement = fmt.Sprintf("UPDATE %s SET %s = CURRENT_TIMESTAMP", table, scope.Quote(softDeleteColumn))
+	}
+
+	if !paginate || q.LimitVal == nil && q.SkipVal == nil {
+		return statement + where, nil
+	}
+
+	order := q.orderBy()
+	limit := ""
+	if q.LimitVal != nil {
+		limit = fmt.Sprintf(" LIMIT %d", *q.LimitVal)
+	}
+
+	switch dialect := q.db.Dialect().GetName(); dialect {
+	case mysqlDialect:
+		if q.SkipVal != nil && *q.SkipVal > 0 {
+			return "", &dbx.DbError{
+				Message: "skip is not supported when deleting with the mysql dialect",
+				Code:    dbx.ErrDbOperation,
+			}
+		}
+		return statement + where + order + limit, nil
+
+	case sqliteDialect, postgresDialect:
+		rowId := "rowid"
+		if dialect == postgresDialect {
+			rowId = "ctid"
+		}
+		if limit == "" {
+			// Both dialects require LIMIT for OFFSET to be used, a negative limit means no limit.
+			limit = " LIMIT -1"
+			if dialect == postgresDialect {
+				limit = " LIMIT ALL"
+			}
+		}
+		offset := ""
+		if q.SkipVal != nil {
+			offset = fmt.Sprintf(" OFFSET %d", *q.SkipVal)
+		}
+		return fmt.Sprintf("%s WHERE %s IN (SELECT %s FROM %s%s%s%s%s)", statement, rowId, rowId, table, where, order, limit, offset), nil
+	}
+
+	return "", &dbx.DbError{
+		Message: fmt.Sprintf("limit and skip are not supported when deleting with the '%s' dialect", q.db.Dialect().GetName()),
+		Code:    dbx.ErrDbOperation,
+	}
+}
+
+// loadForHooks loads the rows matching the query if the model of the query implements the delete hooks. Returns nil
+// if the model is unknown or does not implement them.
+func (q *query) loadForHooks() ([]interface{}, error) {
+	if q.db.Value == nil {
+		return nil, nil
+	}
+	t := reflect.TypeOf(q.db.Value)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	ptr := reflect.PtrTo(t)
+	_, before := ptr.MethodByName(entity.MethodBeforeDelete)
+	_, after := ptr.MethodByName(entity.MethodAfterDelete)
+	if !before && !after {
+		return nil, nil
+	}
+
+	db, err := q.prepare()
+	if err != nil {
+		return nil, err
+	}
+	list := reflect.New(reflect.SliceOf(ptr))
+	if err := db.Find(list.Interface()).Error; err != nil {
+		return nil, err
+	}
+
+	ret := make([]interface{}, list.Elem().Len())
+	for i := range ret {
+		ret[i] = list.Elem().Index(i).Interface()
+	}
+	return ret, nil
+}
+
+// primaryKeyClause builds a where clause that matches the provided records by primary key. Returns false if the model
+// does not have a primary key.
+func (q *query) primaryKeyClause(records []interface{}) (string, []interface{}, bool) {
+	pk := q.db.NewScope(q.db.Value).PrimaryField()
+	if pk == nil {
+		return "", nil, false
+	}
+	values := make([]interface{}, len(records))
+	for i, r := range records {
+		values[i] = q.db.NewScope(r).PrimaryKeyValue()
+	}
+	clause, args, err := buildWhere(q.db, [][]*common.ConditionData{{{Query: dbx.In(pk.DBName, values...)}}})
+	return clause, args, err == nil
+}
+
+// orderBy returns the ORDER BY clause for the sort fields of the query.
+func (q *query) orderBy() string {
+	if len(q.SortFields) == 0 {
+		return ""
+	}
+	b := &clauseBuilder{db: q.db}
+	fields := make([]string, len(q.SortFields))
+	for i, f := range q.SortFields {
+		if strings.HasPrefix(f, "-") {
+			fields[i] = b.quote(f[1:]) + " DESC"
+		} else {
+			fields[i] = b.quote(f)
+		}
+	}
+	return " ORDER BY " + strings.Join(fields, ", ")
+}
@@ -0,0 +1,190 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jucardi/go-db"
+)
+
+// ExecResult contains the outcome of a script that does not return rows. Pass a *ExecResult as the result of `Exec`
+// to obtain it.
+type ExecResult struct {
+	// RowsAffected is the number of rows affected by the script
+	RowsAffected int64
+
+	// LastInsertId is the last id generated by the database, if supported by the driver (not supported by PostgreSQL,
+	// use a 'RETURNING' clause instead)
+	LastInsertId int64
+}
+
+// queryContext is implemented by *sql.DB and *sql.Tx
+type queryContext interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// execResult executes a script that does not return rows and fills the provided *ExecResult
+func (db *database) execResult(script string, result *ExecResult) error {
+	var res sql.Result
+	var err error
+
+	if execer, ok := db.DB.CommonDB().(execContext); ok && db.ctx != nil {
+		res, err = execer.ExecContext(db.ctx, script)
+	} else {
+		res, err = db.DB.CommonDB().Exec(script)
+	}
+	if err != nil {
+		return err
+	}
+
+	result.RowsAffected, _ = res.RowsAffected()
+	result.LastInsertId, _ = res.LastInsertId()
+	return nil
+}
+
+// query executes a script that returns rows and maps them into the result
+func (db *database) query(script string, result interface{}) error {
+	var rows *sql.Rows
+	var err error
+
+	if querier, ok := db.DB.CommonDB().(queryContext); ok && db.ctx != nil {
+		rows, err = querier.QueryContext(db.ctx, script)
+	} else {
+		rows, err = db.DB.CommonDB().Query(script)
+	}
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if err := db.mapRows(rows, result); err != nil {
+		return err
+	}
+	return rows.Err()
+}
+
+// mapRows maps the returned rows into the result, which may be a pointer to a struct, a slice of structs,
+// a map[string]interface{}, a slice of maps or a scalar. A slice of scalars is filled with the first column of each
+// row. Single values are filled with the first row, an error is returned if there are no rows.
+func (db *database) mapRows(rows *sql.Rows, result interface{}) error {
+	rv := reflect.ValueOf(result)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &dbx.DbError{
+			Message: fmt.Sprintf("the result argument must be a non nil pointer, found %T", result),
+			Code:    dbx.ErrDbOperation,
+		}
+	}
+
+	target := rv.Elem()
+	if target.Kind() == reflect.Slice && target.Type().Elem().Kind() != reflect.Uint8 {
+		elemType := target.Type().Elem()
+		isPtr := elemType.Kind() == reflect.Ptr
+		if isPtr {
+			elemType = elemType.Elem()
+		}
+
+		list := reflect.MakeSlice(target.Type(), 0, 0)
+		for rows.Next() {
+			elem := reflect.New(elemType)
+			if err := db.scanRow(rows, elem); err != nil {
+				return err
+			}
+			if isPtr {
+				list = reflect.Append(list, elem)
+			} else {
+				list = reflect.Append(list, elem.Elem())
+			}
+		}
+		target.Set(list)
+		return nil
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return &dbx.DbError{
+			Message: "the script did not return any rows",
+			Code:    dbx.ErrNotFound,
+		}
+	}
+	return db.scanRow(rows, rv)
+}
+
+// scanRow scans the current row into the provided pointer
+func (db *database) scanRow(rows *sql.Rows, ptr reflect.Value) error {
+	t := ptr.Elem().Type()
+
+	switch {
+	case t.Kind() == reflect.Map:
+		if t.Key().Kind() != reflect.String || t.Elem().Kind() != reflect.Interface {
+			return &dbx.DbError{
+				Message: fmt.Sprintf("unsupported map type %s, use map[string]interface{}", t),
+				Code:    dbx.ErrDbOperation,
+			}
+		}
+		row, err := scanMap(rows)
+		if err != nil {
+			return err
+		}
+		m := reflect.MakeMapWithSize(t, len(row))
+		for k, v := range row {
+			if v == nil {
+				m.SetMapIndex(reflect.ValueOf(k), reflect.Zero(t.Elem()))
+			} else {
+				m.SetMapIndex(reflect.ValueOf(k), reflect.ValueOf(v))
+			}
+		}
+		ptr.Elem().Set(m)
+		return nil
+
+	case t.Kind() == reflect.Struct && t != timeType && !ptr.Type().Implements(scannerType):
+		return db.DB.ScanRows(rows, ptr.Interface())
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	dest := make([]interface{}, len(columns))
+	dest[0] = ptr.Interface()
+	for i := 1; i < len(dest); i++ {
+		dest[i] = new(interface{})
+	}
+	return rows.Scan(dest...)
+}
+
+// scanMap scans the current row into a map of column names to values. Byte slices are converted to strings, since
+// most drivers return text columns as []byte.
+func scanMap(rows *sql.Rows) (map[string]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]interface{}, len(columns))
+	for i, c := range columns {
+		if b, ok := values[i].([]byte); ok {
+			ret[c] = string(b)
+		} else {
+			ret[c] = values[i]
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,245 @@
+package sql
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/common"
+)
+
+// numeric matches the string conditions which are primary key values, as gorm does.
+var numeric = regexp.MustCompile(`^\s*\d+\s*$`)
+
+// clauseBuilder translates query conditions into a parameterized SQL where clause, using '?' as placeholder.
+type clauseBuilder struct {
+	db   *gorm.DB
+	args []interface{}
+}
+
+// buildWhere translates the condition blocks of a query into a where clause. Conditions in the same block are joined
+// with AND, and blocks are joined with OR. Returns an empty clause if there are no conditions.
+func buildWhere(db *gorm.DB, blocks [][]*common.ConditionData) (string, []interface{}, error) {
+	b := &clauseBuilder{db: db}
+	var ors []string
+
+	for _, block := range blocks {
+		var ands []string
+		for _, cond := range block {
+			if cond.Query == nil {
+				continue
+			}
+			clause, err := b.condition(cond.Query, cond.Args)
+			if err != nil {
+				return "", nil, err
+			}
+			if cond.Negation {
+				clause = "NOT " + clause
+			}
+			ands = append(ands, clause)
+		}
+		if len(ands) > 0 {
+			ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+		}
+	}
+
+	switch len(ors) {
+	case 0:
+		return "", nil, nil
+	case 1:
+		return ors[0], b.args, nil
+	default:
+		return strings.Join(ors, " OR "), b.args, nil
+	}
+}
+
+// condition translates a single condition, which may be a *dbx.Filter, a raw SQL string with its arguments, a map of
+// column values, a struct whose non-blank fields are used as equality conditions or, as gorm does, a primary key value
+// (an integer or a numeric string) or a slice of them.
+func (b *clauseBuilder) condition(query interface{}, args []interface{}) (string, error) {
+	switch q := query.(type) {
+	case *dbx.Filter:
+		return b.filter(q)
+	case string:
+		if numeric.MatchString(q) {
+			return b.filter(dbx.Eq(b.primaryKey(), strings.TrimSpace(q)))
+		}
+		b.args = append(b.args, args...)
+		return "(" + q + ")", nil
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(query))
+	if v.IsValid() && isPrimaryKey(v.Type()) {
+		return b.filter(dbx.Eq(b.primaryKey(), v.Interface()))
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if elem := v.Type().Elem(); isPrimaryKey(elem) && elem.Kind() != reflect.Uint8 {
+			return b.filter(dbx.In(b.primaryKey(), v.Interface()))
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		keys := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		var filters []*dbx.Filter
+		for _, k := range keys {
+			filters = append(filters, dbx.Eq(k, v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())).Interface()))
+		}
+		return b.filter(dbx.And(filters...))
+	case reflect.Struct:
+		var filters []*dbx.Filter
+		for _, field := range b.db.NewScope(query).Fields() {
+			if !field.IsIgnored && !field.IsBlank && field.Relationship == nil {
+				filters = append(filters, dbx.Eq(field.DBName, field.Field.Interface()))
+			}
+		}
+		return b.filter(dbx.And(filters...))
+	}
+
+	return "", &dbx.DbError{
+		Message: fmt.Sprintf("unsupported query condition of type %T", query),
+		Code:    dbx.ErrDbOperation,
+	}
+}
+
+func (b *clauseBuilder) filter(f *dbx.Filter) (string, error) {
+	if f == nil {
+		return "1 = 1", nil
+	}
+
+	switch f.Op {
+	case dbx.FilterEq:
+		if f.Value == nil {
+			return b.quote(f.Field) + " IS NULL", nil
+		}
+		return b.compare(f, "=")
+	case dbx.FilterNe:
+		if f.Value == nil {
+			return b.quote(f.Field) + " IS NOT NULL", nil
+		}
+		// NULL values are not comparable in SQL, they are matched explicitly as done by MongoDB for missing fields.
+		clause, err := b.compare(f, "<>")
+		return "(" + clause + " OR " + b.quote(f.Field) + " IS NULL)", err
+	case dbx.FilterGt:
+		return b.compare(f, ">")
+	case dbx.FilterGte:
+		return b.compare(f, ">=")
+	case dbx.FilterLt:
+		return b.compare(f, "<")
+	case dbx.FilterLte:
+		return b.compare(f, "<=")
+	case dbx.FilterLike:
+		// MySQL uses backslash escapes in string literals, so the escape character has to be escaped itself
+		if b.db.Dialect().GetName() == mysqlDialect {
+			return b.compare(f, "LIKE", `ESCAPE '\\'`)
+		}
+		return b.compare(f, "LIKE", `ESCAPE '\'`)
+	case dbx.FilterIn:
+		return b.in(f, "IN", "1 = 0")
+	case dbx.FilterNin:
+		return b.in(f, "NOT IN", "1 = 1")
+	case dbx.FilterRegex:
+		switch b.db.Dialect().GetName() {
+		case postgresDialect:
+			return b.compare(f, "~")
+		case mysqlDialect, sqliteDialect:
+			return b.compare(f, "REGEXP")
+		}
+		return "", &dbx.DbError{
+			Message: fmt.Sprintf("regex filters are not supported by the '%s' dialect", b.db.Dialect().GetName()),
+			Code:    dbx.ErrDbOperation,
+		}
+	case dbx.FilterExists:
+		if exists, _ := f.Value.(bool); exists {
+			return b.quote(f.Field) + " IS NOT NULL", nil
+		}
+		return b.quote(f.Field) + " IS NULL", nil
+	case dbx.FilterAnd:
+		return b.join(f.Filters, " AND ", "1 = 1")
+	case dbx.FilterOr:
+		return b.join(f.Filters, " OR ", "1 = 0")
+	case dbx.FilterNot:
+		clause, err := b.join(f.Filters, " AND ", "1 = 1")
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + clause, nil
+	}
+
+	return "", &dbx.DbError{
+		Message: fmt.Sprintf("unsupported filter operator '%s'", f.Op),
+		Code:    dbx.ErrDbOperation,
+	}
+}
+
+func (b *clauseBuilder) compare(f *dbx.Filter, op string, suffix ...string) (string, error) {
+	b.args = append(b.args, f.Value)
+	clause := fmt.Sprintf("%s %s ?", b.quote(f.Field), op)
+	if len(suffix) > 0 {
+		clause += " " + suffix[0]
+	}
+	return clause, nil
+}
+
+func (b *clauseBuilder) in(f *dbx.Filter, op, empty string) (string, error) {
+	values := f.Values()
+	if len(values) == 0 {
+		return empty, nil
+	}
+	b.args = append(b.args, values...)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	return fmt.Sprintf("%s %s (%s)", b.quote(f.Field), op, placeholders), nil
+}
+
+func (b *clauseBuilder) join(filters []*dbx.Filter, sep, empty string) (string, error) {
+	if len(filters) == 0 {
+		return empty, nil
+	}
+	clauses := make([]string, len(filters))
+	for i, f := range filters {
+		clause, err := b.filter(f)
+		if err != nil {
+			return "", err
+		}
+		clauses[i] = clause
+	}
+	return "(" + strings.Join(clauses, sep) + ")", nil
+}
+
+// primaryKey returns the primary key column of the model of the query, or 'id' if the model is unknown.
+func (b *clauseBuilder) primaryKey() string {
+	if b.db.Value != nil {
+		if pk := b.db.NewScope(b.db.Value).PrimaryKey(); pk != "" {
+			return pk
+		}
+	}
+	return "id"
+}
+
+// isPrimaryKey indicates whether values of the provided type are used as primary key values when passed as conditions.
+func isPrimaryKey(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// quote quotes a field name using the dialect of the database. Nested fields separated by a dot are quoted per part.
+func (b *clauseBuilder) quote(field string) string {
+	parts := strings.Split(field, ".")
+	for i, p := range parts {
+		parts[i] = b.db.Dialect().Quote(p)
+	}
+	return strings.Join(parts, ".")
+}
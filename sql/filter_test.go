@@ -0,0 +1,127 @@
+package sql
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/common"
+	. "github.com/jucardi/go-testx/testx"
+)
+
+type pkModel struct {
+	Code int `gorm:"primary_key"`
+	Name string
+}
+
+// dialectDB returns a *gorm.DB which builds the SQL of the provided dialect, backed by an in-memory SQLite database
+// since the translation does not need a connection to the actual engine.
+func dialectDB(t *testing.T, dialect string) *gorm.DB {
+	conn, err := sql.Open(sqliteDialect, SQLiteMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(dialect, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestFilterToSQL(t *testing.T) {
+	db := dialectDB(t, sqliteDialect)
+
+	cases := []struct {
+		name   string
+		filter *dbx.Filter
+		clause string
+		args   []interface{}
+	}{
+		{"Eq", dbx.Eq("name", "bob"), `"name" = ?`, []interface{}{"bob"}},
+		{"Eq nil", dbx.Eq("name", nil), `"name" IS NULL`, nil},
+		{"Ne", dbx.Ne("name", "bob"), `("name" <> ? OR "name" IS NULL)`, []interface{}{"bob"}},
+		{"Ne nil", dbx.Ne("name", nil), `"name" IS NOT NULL`, nil},
+		{"Gt", dbx.Gt("age", 1), `"age" > ?`, []interface{}{1}},
+		{"Gte", dbx.Gte("age", 1), `"age" >= ?`, []interface{}{1}},
+		{"Lt", dbx.Lt("age", 1), `"age" < ?`, []interface{}{1}},
+		{"Lte", dbx.Lte("age", 1), `"age" <= ?`, []interface{}{1}},
+		{"Like", dbx.Like("name", `50\%%`), `"name" LIKE ? ESCAPE '\'`, []interface{}{`50\%%`}},
+		{"Regex", dbx.Regex("name", "^b"), `"name" REGEXP ?`, []interface{}{"^b"}},
+		{"In", dbx.In("age", []int{1, 2}), `"age" IN (?, ?)`, []interface{}{1, 2}},
+		{"Empty In", dbx.In("age"), `1 = 0`, nil},
+		{"Nin", dbx.Nin("age", 1, 2), `"age" NOT IN (?, ?)`, []interface{}{1, 2}},
+		{"Empty Nin", dbx.Nin("age"), `1 = 1`, nil},
+		{"Exists", dbx.Exists("email"), `"email" IS NOT NULL`, nil},
+		{"Not exists", dbx.Exists("email", false), `"email" IS NULL`, nil},
+		{"Not", dbx.Not(dbx.Eq("name", "bob")), `NOT ("name" = ?)`, []interface{}{"bob"}},
+		{"Empty And", dbx.And(), `1 = 1`, nil},
+		{"Empty Or", dbx.Or(), `1 = 0`, nil},
+		{"Nested", dbx.And(dbx.Eq("status", "active"), dbx.Or(dbx.Lt("age", 18), dbx.Exists("guardian"))),
+			`("status" = ? AND ("age" < ? OR "guardian" IS NOT NULL))`, []interface{}{"active", 18}},
+		{"Dotted field", dbx.Eq("users.name", "bob"), `"users"."name" = ?`, []interface{}{"bob"}},
+	}
+
+	for _, c := range cases {
+		c := c
+		Convey(c.name, t, func() {
+			b := &clauseBuilder{db: db}
+			clause, err := b.filter(c.filter)
+			ShouldBeNil(err)
+			ShouldEqual(c.clause, clause)
+			ShouldEqual(c.args, b.args)
+		})
+	}
+}
+
+func TestFilterToSQLDialects(t *testing.T) {
+	Convey("Regex and Like depend on the dialect", t, func() {
+		b := &clauseBuilder{db: dialectDB(t, postgresDialect)}
+		clause, err := b.filter(dbx.Regex("name", "^b"))
+		ShouldBeNil(err)
+		ShouldEqual(`"name" ~ ?`, clause)
+
+		b = &clauseBuilder{db: dialectDB(t, mysqlDialect)}
+		clause, err = b.filter(dbx.Regex("name", "^b"))
+		ShouldBeNil(err)
+		ShouldEqual("`name` REGEXP ?", clause)
+		clause, err = b.filter(dbx.Like("name", "b%"))
+		ShouldBeNil(err)
+		ShouldEqual("`name` LIKE ? ESCAPE '\\\\'", clause)
+
+		b = &clauseBuilder{db: dialectDB(t, "common")}
+		_, err = b.filter(dbx.Regex("name", "^b"))
+		ShouldNotBeNil(err)
+	})
+}
+
+func TestBuildWhere(t *testing.T) {
+	db := dialectDB(t, sqliteDialect)
+
+	Convey("Blocks are joined with OR and their conditions with AND", t, func() {
+		clause, args, err := buildWhere(db, [][]*common.ConditionData{
+			{{Query: dbx.Eq("a", 1)}, {Query: "b = ?", Args: []interface{}{2}, Negation: true}},
+			{{Query: map[string]interface{}{"c": 3}}},
+		})
+		ShouldBeNil(err)
+		ShouldEqual(`("a" = ? AND NOT (b = ?)) OR (("c" = ?))`, clause)
+		ShouldEqual([]interface{}{1, 2, 3}, args)
+	})
+	Convey("Scalar conditions match the primary key", t, func() {
+		clause, args, err := buildWhere(db, [][]*common.ConditionData{{{Query: 5}}})
+		ShouldBeNil(err)
+		ShouldEqual(`("id" = ?)`, clause)
+		ShouldEqual([]interface{}{5}, args)
+
+		model := db.Model(&pkModel{})
+		clause, args, err = buildWhere(model, [][]*common.ConditionData{{{Query: "7"}}, {{Query: []uint{1, 2}}}})
+		ShouldBeNil(err)
+		ShouldEqual(`("code" = ?) OR ("code" IN (?, ?))`, clause)
+		ShouldEqual([]interface{}{"7", uint(1), uint(2)}, args)
+	})
+	Convey("Unsupported conditions are rejected", t, func() {
+		_, _, err := buildWhere(db, [][]*common.ConditionData{{{Query: 1.5}}})
+		ShouldNotBeNil(err)
+	})
+}
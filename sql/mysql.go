@@ -8,6 +8,8 @@ import (
 	"github.com/jucardi/go-strings/stringx"
 )
 
+const mysqlDialect = "mysql"
+
 type provider struct {
 }
 
@@ -26,7 +28,7 @@ func init() {
 // the cluster, so the seed servers are used only to find out about the cluster
 // topology.
 func Dial(cfg *dbx.DbConfig) (IDatabase, error) {
-	db, err := gorm.Open("mysql", getUrl(cfg))
+	db, err := gorm.Open(mysqlDialect, getUrl(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("unable to connect to mysql, %s", err.Error())
 	}
@@ -45,7 +47,7 @@ func getUrl(cfg *dbx.DbConfig) string {
 		builder.Append("@")
 	}
 
-	builder.Append("tpc(", cfg.Host)
+	builder.Append("tcp(", cfg.Host)
 	if cfg.Port > 0 {
 		builder.Appendf(":%d", cfg.Port)
 	}
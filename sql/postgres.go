@@ -0,0 +1,136 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/logger"
+	"github.com/jucardi/go-strings/stringx"
+)
+
+const postgresDialect = "postgres"
+
+type postgresProvider struct {
+}
+
+func (p *postgresProvider) Dial(cfg *dbx.DbConfig) (dbx.IDatabase, error) {
+	return DialPostgres(cfg)
+}
+
+func init() {
+	if err := dbx.Register("PostgreSQL", &postgresProvider{}); err != nil {
+		logger.Get().Error("Unable to register PostgreSQL provider, ", err.Error())
+	}
+}
+
+// DialPostgres establishes a connection to a PostgreSQL database. See `getPostgresDSN` for how the configuration is
+// translated into a data source name.
+func DialPostgres(cfg *dbx.DbConfig) (IDatabase, error) {
+	db, err := gorm.Open(postgresDialect, getPostgresDSN(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to postgres, %s", err.Error())
+	}
+	return FromDB(db, false), nil
+}
+
+// getPostgresDSN builds the data source name for the provided configuration.
+//
+// If `cfg.Host` is a connection URL (postgres://...), it is used as is and `cfg.Options` are added as query
+// parameters. Otherwise, a key/value DSN is built with the host, port, credentials and database name, plus the
+// parameters in `cfg.Options`, which may be separated by '&' or spaces, e.g. "sslmode=disable&search_path=app".
+func getPostgresDSN(cfg *dbx.DbConfig) string {
+	options := parseOptions(cfg.Options)
+
+	if strings.HasPrefix(cfg.Host, "postgres://") || strings.HasPrefix(cfg.Host, "postgresql://") {
+		builder := stringx.Builder().Append(cfg.Host)
+		sep := "?"
+		if strings.Contains(cfg.Host, "?") {
+			sep = "&"
+		}
+		for _, o := range options {
+			builder.Append(sep, o[0], "=", o[1])
+			sep = "&"
+		}
+		return builder.Build()
+	}
+
+	var pairs []string
+	add := func(key, value string) {
+		if value != "" {
+			pairs = append(pairs, key+"="+quoteDSNValue(value))
+		}
+	}
+
+	add("host", cfg.Host)
+	if cfg.Port > 0 {
+		add("port", fmt.Sprint(cfg.Port))
+	}
+	add("user", cfg.Username)
+	add("password", cfg.Password)
+	add("dbname", cfg.Database)
+	for _, o := range options {
+		add(o[0], o[1])
+	}
+	return strings.Join(pairs, " ")
+}
+
+// parseOptions parses connection options in the 'key=value' format, separated by '&' or spaces. A leading '?' is
+// ignored.
+func parseOptions(options string) (ret [][2]string) {
+	fields := strings.FieldsFunc(strings.TrimPrefix(strings.TrimSpace(options), "?"), func(r rune) bool {
+		return r == '&' || r == ' '
+	})
+	for _, f := range fields {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) == 2 && kv[0] != "" {
+			ret = append(ret, [2]string{kv[0], kv[1]})
+		}
+	}
+	return
+}
+
+// quoteDSNValue quotes a value of a key/value DSN if it is required, as described in
+// https://www.postgresql.org/docs/current/libpq-connect.html#LIBPQ-CONNSTRING
+func quoteDSNValue(value string) string {
+	if !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// splitSchema splits a table name in the 'schema.table' format. The schema is empty if the name is not qualified.
+func splitSchema(name string) (schema, table string) {
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// postgresHasTable checks whether a table exists. Unlike gorm, supports names qualified by schema. Unqualified names
+// are looked up in the current schema.
+func postgresHasTable(db *gorm.DB, name string) bool {
+	schema, table := splitSchema(name)
+	var count int
+	err := db.CommonDB().QueryRow(
+		"SELECT count(*) FROM information_schema.tables WHERE table_schema = COALESCE(NULLIF($1, ''), CURRENT_SCHEMA()) AND table_name = $2 AND table_type = 'BASE TABLE'",
+		schema, table,
+	).Scan(&count)
+	if err != nil {
+		logger.Get().Error(fmt.Sprintf("Unable to verify whether table '%s' exists, %s", name, err.Error()))
+	}
+	return count > 0
+}
+
+// postgresEnsureSchema creates the schema of a qualified table name if it does not exist.
+func postgresEnsureSchema(db *gorm.DB, name string) error {
+	schema, _ := splitSchema(name)
+	if schema == "" {
+		return nil
+	}
+	return db.Exec("CREATE SCHEMA IF NOT EXISTS " + db.Dialect().Quote(schema)).Error
+}
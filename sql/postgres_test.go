@@ -0,0 +1,29 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/jucardi/go-db"
+	. "github.com/jucardi/go-testx/testx"
+)
+
+func TestPostgresDSN(t *testing.T) {
+	Convey("Key/value DSN from the configuration and options", t, func() {
+		dsn := getPostgresDSN(&dbx.DbConfig{
+			Host:     "localhost",
+			Port:     5432,
+			Username: "admin",
+			Password: "it's secret",
+			Database: "app",
+			Options:  "sslmode=disable&search_path=app,public",
+		})
+		ShouldEqual(`host=localhost port=5432 user=admin password='it\'s secret' dbname=app sslmode=disable search_path=app,public`, dsn)
+	})
+	Convey("Connection URLs are used as they are", t, func() {
+		dsn := getPostgresDSN(&dbx.DbConfig{
+			Host:    "postgres://admin@localhost/app",
+			Options: "?sslmode=require",
+		})
+		ShouldEqual("postgres://admin@localhost/app?sslmode=require", dsn)
+	})
+}
@@ -1,11 +1,16 @@
 package sql
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"github.com/jinzhu/gorm"
 	"github.com/jucardi/go-db"
 	"github.com/jucardi/go-db/common"
+	"github.com/jucardi/go-db/entity"
+	"github.com/jucardi/go-db/logger"
 	"github.com/jucardi/go-db/pages"
+	"reflect"
 	"strings"
 )
 
@@ -55,24 +60,35 @@ type IQuery interface {
 	Rows() (*sql.Rows, error)
 
 	Error() error
+
+	IRowsAffected
 }
 
-func newQuery(db *gorm.DB, table string) *query {
-	return &query{
-		table:   table,
-		DB:      db,
-		queries: []*gorm.DB{db.Table(table)},
-	}
+// IRowsAffected is implemented by the queries of this package to report the number of rows affected by the last
+// `Update`, `Delete` or `Remove` executed by the query.
+type IRowsAffected interface {
+	RowsAffected() int64
 }
 
-type query struct {
-	*gorm.DB
-	queries []*gorm.DB
-	table   string
+func newQuery(db *gorm.DB, table string, callbacks *common.CallbacksManager, ctx context.Context) *query {
+	ret := &query{
+		db:        db,
+		table:     table,
+		callbacks: callbacks,
+	}
+	ret.AbstractQuery = &common.AbstractQuery{
+		Q:   ret,
+		Ctx: ctx,
+	}
+	return ret
 }
 
-func (q *query) current() *gorm.DB {
-	return q.queries[len(q.queries)-1]
+type query struct {
+	*common.AbstractQuery
+	db           *gorm.DB
+	table        string
+	callbacks    *common.CallbacksManager
+	rowsAffected int64
 }
 
 // Page adds to the query the information required to fetch the requested page of objects.
@@ -85,54 +101,116 @@ func (q *query) WrapPage(result interface{}, page ...*pages.Page) (*pages.Pagina
 	return common.WrapPageHandler(q, result, page...)
 }
 
-func (q *query) Limit(n int) dbx.IQuery {
-	q.DB.Limit(n)
-	return q
-}
-
-func (q *query) Skip(n int) dbx.IQuery {
-	q.DB.Offset(n)
-	return q
-}
-
-func (q *query) Sort(fields ...string) dbx.IQuery {
-	for _, field := range fields {
-		if strings.HasPrefix(field, "-") {
-			q.DB.Order(field[1:] + " desc")
-		} else {
-			q.DB.Order(field)
+// WrapCursor attempts to obtain the items in the subset requested by the cursor using keyset pagination. The primary
+// key of the model is used to make the order deterministic.
+func (q *query) WrapCursor(result interface{}, cursor *pages.Cursor) (*pages.Paginated, error) {
+	uniqueField := ""
+	if elem := reflect.TypeOf(result); elem != nil && elem.Kind() == reflect.Ptr && elem.Elem().Kind() == reflect.Slice {
+		model := elem.Elem().Elem()
+		for model.Kind() == reflect.Ptr {
+			model = model.Elem()
+		}
+		if model.Kind() == reflect.Struct {
+			if pk := q.db.NewScope(reflect.New(model).Interface()).PrimaryField(); pk != nil {
+				uniqueField = pk.DBName
+			}
 		}
 	}
-	return q
+	return common.WrapCursorHandler(q, result, cursor, uniqueField, q.fieldValue)
 }
 
-func (q *query) Select(query interface{}, args ...interface{}) dbx.IQuery {
-	q.current().Select(query, args...)
-	return q
+// fieldValue obtains the value of a column from a record, or of a key if the record is a map.
+func (q *query) fieldValue(item interface{}, field string) (interface{}, error) {
+	if i := strings.LastIndex(field, "."); i >= 0 {
+		field = field[i+1:]
+	}
+	v := reflect.Indirect(reflect.ValueOf(item))
+	if v.Kind() == reflect.Map {
+		if value := v.MapIndex(reflect.ValueOf(field)); value.IsValid() {
+			return value.Interface(), nil
+		}
+		return nil, nil
+	}
+	for _, f := range q.db.NewScope(item).Fields() {
+		if f.DBName == field {
+			return f.Field.Interface(), nil
+		}
+	}
+	return nil, fmt.Errorf("column '%s' not found in %T", field, item)
 }
 
-func (q *query) Where(condition interface{}, args ...interface{}) dbx.IQuery {
-	q.current().Where(condition, args...)
-	return q
-}
+// EstimatedCount returns the estimation of the amount of rows of the table kept by the database statistics, from
+// 'information_schema' for MySQL and 'pg_class' for PostgreSQL. Not available for other databases, or if the query has
+// conditions.
+func (q *query) EstimatedCount() (n int, ok bool, err error) {
+	var stmt string
+	switch q.db.Dialect().GetName() {
+	case mysqlDialect:
+		stmt = "SELECT table_rows FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	case postgresDialect:
+		stmt = "SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass(?)"
+	default:
+		return 0, false, nil
+	}
+	if q.table == "" || q.HasConditions() {
+		return 0, false, nil
+	}
 
-func (q *query) Not(condition interface{}, args ...interface{}) dbx.IQuery {
-	q.current().Not(condition, args...)
-	return q
+	scope := q.newScope(dbx.OpQuery)
+	scope.Result = &n
+	err = q.callbacks.Run(scope, func() error {
+		if err := q.ctxErr(); err != nil {
+			return err
+		}
+		var rows sql.NullInt64
+		if err := q.db.Raw(stmt, q.table).Row().Scan(&rows); err != nil {
+			return err
+		}
+		// PostgreSQL reports -1 (or 0 before version 14) for tables which were never analyzed
+		n, ok = int(rows.Int64), rows.Valid && rows.Int64 > 0
+		return nil
+	})
+	return
 }
 
-func (q *query) Or() dbx.IQuery {
-	q.queries = append(q.queries, q.DB.Table(q.table))
-	return q
+// samePointer indicates whether both values are the same pointer
+func samePointer(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	return va.Kind() == reflect.Ptr && vb.Kind() == reflect.Ptr && va.Pointer() == vb.Pointer()
 }
 
 func (q *query) Count() (n int, err error) {
-	err = q.prepare().Count(&n).Error
+	scope := q.newScope(dbx.OpQuery)
+	scope.Result = &n
+	err = q.callbacks.Run(scope, func() error {
+		if err := q.ctxErr(); err != nil {
+			return err
+		}
+		db, err := q.where()
+		if err != nil {
+			return err
+		}
+		return db.Count(&n).Error
+	})
 	return
 }
 
 func (q *query) First(result interface{}) error {
-	return q.prepare().First(result).Error
+	scope := q.newScope(dbx.OpQuery)
+	scope.Result = result
+	return q.callbacks.Run(scope, func() error {
+		if err := q.ctxErr(); err != nil {
+			return err
+		}
+		db, err := q.prepare()
+		if err != nil {
+			return err
+		}
+		if err := db.First(result).Error; err != nil {
+			return err
+		}
+		return entity.InvokeResult(entity.MethodAfterFound, result)
+	})
 }
 
 func (q *query) One(result interface{}) error {
@@ -140,40 +218,167 @@ func (q *query) One(result interface{}) error {
 }
 
 func (q *query) Last(result interface{}) error {
-	return q.prepare().Last(result).Error
+	scope := q.newScope(dbx.OpQuery)
+	scope.Result = result
+	return q.callbacks.Run(scope, func() error {
+		if err := q.ctxErr(); err != nil {
+			return err
+		}
+		db, err := q.prepare()
+		if err != nil {
+			return err
+		}
+		if err := db.Last(result).Error; err != nil {
+			return err
+		}
+		return entity.InvokeResult(entity.MethodAfterFound, result)
+	})
 }
 
 func (q *query) All(result interface{}) error {
-	return q.prepare().Scan(result).Error
+	scope := q.newScope(dbx.OpQuery)
+	scope.Result = result
+	return q.callbacks.Run(scope, func() error {
+		if err := q.ctxErr(); err != nil {
+			return err
+		}
+		db, err := q.prepare()
+		if err != nil {
+			return err
+		}
+		if err := db.Scan(result).Error; err != nil {
+			return err
+		}
+		return entity.InvokeResult(entity.MethodAfterFound, result)
+	})
 }
 
 func (q *query) Distinct(key string, result interface{}) error {
-	return q.prepare().Select("DISTINCT ?", key).Scan(result).Error
+	scope := q.newScope(dbx.OpQuery)
+	scope.Result = result
+	return q.callbacks.Run(scope, func() error {
+		if err := q.ctxErr(); err != nil {
+			return err
+		}
+		db, err := q.where()
+		if err != nil {
+			return err
+		}
+		return db.Pluck("DISTINCT "+db.Dialect().Quote(key), result).Error
+	})
 }
 
+// Update updates the records resulting from executing the query. The BeforeUpdate and AfterUpdate hooks of the update
+// document are invoked around the update, unless it is the model of the query, for which they are invoked by gorm.
 func (q *query) Update(update interface{}) error {
-	return q.prepare().Updates(update).Error
+	hooks := !samePointer(q.db.Value, update)
+	if hooks {
+		if err := entity.Invoke(entity.MethodBeforeUpdate, update); err != nil {
+			return err
+		}
+	}
+	scope := q.newScope(dbx.OpUpdate)
+	scope.Documents = []interface{}{update}
+	return q.callbacks.Run(scope, func() error {
+		if err := q.ctxErr(); err != nil {
+			return err
+		}
+		db, err := q.where()
+		if err != nil {
+			return err
+		}
+		result := db.Updates(update)
+		q.rowsAffected = result.RowsAffected
+		if result.Error != nil || !hooks {
+			return result.Error
+		}
+		return entity.Invoke(entity.MethodAfterUpdate, update)
+	})
 }
 
 func (q *query) Delete() error {
-	panic("")
+	return q.Remove()
 }
 
 func (q *query) Remove() error {
-	panic("implement me")
+	return q.callbacks.Run(q.newScope(dbx.OpDelete), func() (err error) {
+		if err = q.ctxErr(); err != nil {
+			return
+		}
+		q.rowsAffected, err = q.remove()
+		return
+	})
+}
+
+func (q *query) RowsAffected() int64 {
+	return q.rowsAffected
 }
 
 func (q *query) Row() *sql.Row {
-	return q.prepare().Row()
+	db, err := q.prepare()
+	if err != nil {
+		logger.Get().Error(fmt.Sprintf("Unable to build the query conditions, %s", err.Error()))
+		return q.db.Where("1 = 0").Row()
+	}
+	return db.Row()
 }
 
 func (q *query) Rows() (*sql.Rows, error) {
-	return q.prepare().Rows()
+	db, err := q.prepare()
+	if err != nil {
+		return nil, err
+	}
+	return db.Rows()
+}
+
+// ctxErr returns the error of the context bound to the query if it is done. gorm v1 does not support contexts, so the
+// context is checked before the statement is sent, but a statement that already started is not interrupted.
+func (q *query) ctxErr() error {
+	if q.Ctx == nil {
+		return nil
+	}
+	return q.Ctx.Err()
+}
+
+func (q *query) newScope(op dbx.Operation) *dbx.Scope {
+	return &dbx.Scope{
+		Context:   q.Ctx,
+		Operation: op,
+		Repo:      q.table,
+		Condition: q.Queries,
+	}
+}
+
+// where applies the query conditions to the underlying *gorm.DB
+func (q *query) where() (*gorm.DB, error) {
+	clause, args, err := buildWhere(q.db, q.Queries)
+	if err != nil || clause == "" {
+		return q.db, err
+	}
+	return q.db.Where(clause, args...), nil
 }
 
-func (q *query) prepare() *gorm.DB {
-	for _, qry := range q.queries {
-		q.DB.Where(qry.SubQuery())
+// prepare applies the query conditions, sorting, pagination and selected fields to the underlying *gorm.DB
+func (q *query) prepare() (*gorm.DB, error) {
+	db, err := q.where()
+	if err != nil {
+		return nil, err
 	}
-	return q.DB
-}
\ No newline at end of file
+	for _, field := range q.SortFields {
+		if strings.HasPrefix(field, "-") {
+			db = db.Order(field[1:] + " desc")
+		} else {
+			db = db.Order(field)
+		}
+	}
+	if q.SkipVal != nil {
+		db = db.Offset(*q.SkipVal)
+	}
+	if q.LimitVal != nil {
+		db = db.Limit(*q.LimitVal)
+	}
+	for _, cond := range q.Selects {
+		db = db.Select(cond.Query, cond.Args...)
+	}
+	return db, nil
+}
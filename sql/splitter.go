@@ -0,0 +1,235 @@
+package sql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Statement is a statement of a SQL script
+type Statement struct {
+	// Text is the statement without the delimiter
+	Text string
+
+	// Line is the line of the script where the statement begins, starting at 1
+	Line int
+}
+
+var (
+	delimiterCommand = regexp.MustCompile(`(?i)^[ \t]*DELIMITER[ \t]+(\S+)[ \t]*$`)
+	triggerStatement = regexp.MustCompile(`(?is)^CREATE\s+(TEMP\s+|TEMPORARY\s+)?TRIGGER\s`)
+	endsWithEnd      = regexp.MustCompile(`(?i)(^|\s)END$`)
+)
+
+// SplitScript splits a SQL script into its statements, so they can be executed one by one. The splitter is aware of
+// the syntax of the dialect ('mysql', 'postgres', 'sqlite3'):
+//
+//   - Delimiters inside quoted strings, quoted identifiers and comments are ignored. Comments are kept in the
+//     statements, statements that only contain comments are skipped.
+//   - `DELIMITER` commands change the delimiter of the following statements, as supported by the MySQL client, e.g. to
+//     create stored procedures. The command must be in its own line.
+//   - PostgreSQL dollar-quoted strings ($$ ... $$ or $tag$ ... $tag$) are not split, e.g. function bodies.
+//   - SQLite 'CREATE TRIGGER' statements are not split before their 'END'.
+func SplitScript(script, dialect string) ([]Statement, error) {
+	s := &splitter{
+		script:    script,
+		dialect:   dialect,
+		delimiter: ";",
+		line:      1,
+	}
+	return s.split()
+}
+
+type splitter struct {
+	script    string
+	dialect   string
+	delimiter string
+	line      int
+
+	statements []Statement
+	current    strings.Builder
+	startLine  int
+	hasCode    bool
+	codeStart  int
+}
+
+func (s *splitter) split() ([]Statement, error) {
+	i := 0
+	lineStart := true
+
+	for i < len(s.script) {
+		if lineStart {
+			if n, ok := s.delimiterCommand(i); ok {
+				i = n
+				continue
+			}
+		}
+		lineStart = false
+
+		c := s.script[i]
+		switch {
+		case c == '\n':
+			s.write(i, i+1)
+			s.line++
+			lineStart = true
+			i++
+
+		case strings.HasPrefix(s.script[i:], s.delimiter) && s.delimiterEndsStatement():
+			s.flush()
+			i += len(s.delimiter)
+
+		case c == '\'' || c == '"' || c == '`' && s.dialect == mysqlDialect:
+			n, err := s.quoted(i, c)
+			if err != nil {
+				return nil, err
+			}
+			i = n
+
+		case c == '-' && strings.HasPrefix(s.script[i:], "--"), c == '#' && s.dialect == mysqlDialect:
+			n := strings.IndexByte(s.script[i:], '\n')
+			if n < 0 {
+				n = len(s.script) - i
+			}
+			s.write(i, i+n)
+			i += n
+
+		case c == '/' && strings.HasPrefix(s.script[i:], "/*"):
+			n := strings.Index(s.script[i+2:], "*/")
+			if n < 0 {
+				return nil, fmt.Errorf("unterminated comment at line %d", s.line)
+			}
+			s.line += strings.Count(s.script[i:i+n+4], "\n")
+			s.write(i, i+n+4)
+			i += n + 4
+
+		case c == '$' && s.dialect == postgresDialect:
+			n, err := s.dollarQuoted(i)
+			if err != nil {
+				return nil, err
+			}
+			i = n
+
+		default:
+			if c != ' ' && c != '\t' && c != '\r' {
+				s.code()
+			}
+			s.write(i, i+1)
+			i++
+		}
+	}
+
+	s.flush()
+	return s.statements, nil
+}
+
+// delimiterCommand processes a 'DELIMITER' command at the line that begins at 'i'. Returns the position of the next
+// line if the line is a command.
+func (s *splitter) delimiterCommand(i int) (int, bool) {
+	end := strings.IndexByte(s.script[i:], '\n')
+	if end < 0 {
+		end = len(s.script) - i
+	}
+	m := delimiterCommand.FindStringSubmatch(strings.TrimSuffix(s.script[i:i+end], "\r"))
+	if m == nil {
+		return 0, false
+	}
+	s.flush()
+	s.delimiter = m[1]
+	if i+end < len(s.script) {
+		s.line++
+		end++
+	}
+	return i + end, true
+}
+
+// delimiterEndsStatement indicates whether a delimiter found ends the current statement. SQLite triggers contain
+// statements ended by ';' in their body, they end with 'END;'.
+func (s *splitter) delimiterEndsStatement() bool {
+	if s.dialect != sqliteDialect || s.delimiter != ";" || !s.hasCode {
+		return true
+	}
+	text := strings.TrimSpace(s.current.String()[s.codeStart:])
+	return !triggerStatement.MatchString(text) || endsWithEnd.MatchString(text)
+}
+
+// quoted writes the string or identifier quoted by 'q' that begins at 'i', and returns the position after it.
+func (s *splitter) quoted(i int, q byte) (int, error) {
+	line := s.line
+	s.code()
+	j := i + 1
+	for j < len(s.script) {
+		switch c := s.script[j]; {
+		case c == '\\' && s.dialect == mysqlDialect && q != '`':
+			if j+1 < len(s.script) && s.script[j+1] == '\n' {
+				s.line++
+			}
+			j += 2
+			continue
+		case c == '\n':
+			s.line++
+		case c == q:
+			if j+1 < len(s.script) && s.script[j+1] == q {
+				j += 2
+				continue
+			}
+			s.write(i, j+1)
+			return j + 1, nil
+		}
+		j++
+	}
+	return 0, fmt.Errorf("unterminated quoted string at line %d", line)
+}
+
+// dollarQuoted writes the dollar-quoted string that begins at 'i', and returns the position after it. A '$' that does
+// not begin a dollar-quoted string (e.g. a '$1' parameter) is written as is.
+func (s *splitter) dollarQuoted(i int) (int, error) {
+	s.code()
+	end := strings.IndexByte(s.script[i+1:], '$')
+	if end < 0 || !isDollarTag(s.script[i+1:i+1+end]) {
+		s.write(i, i+1)
+		return i + 1, nil
+	}
+
+	tag := s.script[i : i+end+2]
+	n := strings.Index(s.script[i+len(tag):], tag)
+	if n < 0 {
+		return 0, fmt.Errorf("unterminated dollar-quoted string at line %d", s.line)
+	}
+	next := i + len(tag) + n + len(tag)
+	s.line += strings.Count(s.script[i:next], "\n")
+	s.write(i, next)
+	return next, nil
+}
+
+func isDollarTag(tag string) bool {
+	for i, c := range tag {
+		if c != '_' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && (i == 0 || !(c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// code marks the current statement as containing code, as opposed to only comments or white spaces
+func (s *splitter) code() {
+	if !s.hasCode {
+		s.hasCode = true
+		s.startLine = s.line
+		s.codeStart = s.current.Len()
+	}
+}
+
+func (s *splitter) write(from, to int) {
+	s.current.WriteString(s.script[from:to])
+}
+
+func (s *splitter) flush() {
+	if s.hasCode {
+		s.statements = append(s.statements, Statement{
+			Text: strings.TrimSpace(s.current.String()),
+			Line: s.startLine,
+		})
+	}
+	s.current.Reset()
+	s.hasCode = false
+}
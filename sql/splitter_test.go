@@ -0,0 +1,58 @@
+package sql
+
+import (
+	"testing"
+
+	. "github.com/jucardi/go-testx/testx"
+)
+
+func statementTexts(statements []Statement) (ret []string) {
+	for _, s := range statements {
+		ret = append(ret, s.Text)
+	}
+	return
+}
+
+func TestSplitScript(t *testing.T) {
+	Convey("Delimiters in strings and comments are ignored", t, func() {
+		statements, err := SplitScript("-- users;\nCREATE TABLE users (name TEXT);\n\nINSERT INTO users VALUES ('a;b'), (\"c;d\"); /* ; */\n-- only a comment;\n", sqliteDialect)
+		ShouldBeNil(err)
+		ShouldEqual([]string{"-- users;\nCREATE TABLE users (name TEXT)", "INSERT INTO users VALUES ('a;b'), (\"c;d\")"}, statementTexts(statements))
+		ShouldEqual(2, statements[0].Line)
+		ShouldEqual(4, statements[1].Line)
+	})
+	Convey("Lines in block comments are counted", t, func() {
+		statements, err := SplitScript("/*\n * users\n */\nCREATE TABLE users (name TEXT);\nSELECT /* a\nb */ 1;\nSELECT 2;", sqliteDialect)
+		ShouldBeNil(err)
+		ShouldLen(statements, 3)
+		ShouldEqual(4, statements[0].Line)
+		ShouldEqual(5, statements[1].Line)
+		ShouldEqual(7, statements[2].Line)
+	})
+	Convey("DELIMITER commands change the delimiter", t, func() {
+		script := "DROP PROCEDURE IF EXISTS p;\nDELIMITER //\nCREATE PROCEDURE p()\nBEGIN\n  SELECT 'it\\'s';\nEND //\nDELIMITER ;\nCALL p();"
+		statements, err := SplitScript(script, mysqlDialect)
+		ShouldBeNil(err)
+		ShouldEqual([]string{"DROP PROCEDURE IF EXISTS p", "CREATE PROCEDURE p()\nBEGIN\n  SELECT 'it\\'s';\nEND", "CALL p()"}, statementTexts(statements))
+		ShouldEqual(3, statements[1].Line)
+		ShouldEqual(8, statements[2].Line)
+	})
+	Convey("Dollar-quoted strings are not split", t, func() {
+		script := "CREATE FUNCTION f() RETURNS int AS $body$\nBEGIN\n  RETURN 1;\nEND;\n$body$ LANGUAGE plpgsql;\nSELECT $1;"
+		statements, err := SplitScript(script, postgresDialect)
+		ShouldBeNil(err)
+		ShouldLen(statements, 2)
+		ShouldEqual(6, statements[1].Line)
+	})
+	Convey("SQLite triggers are not split before END", t, func() {
+		script := "CREATE TRIGGER t AFTER INSERT ON users BEGIN\n  UPDATE users SET name = 'x';\nEND;\nSELECT 1;"
+		statements, err := SplitScript(script, sqliteDialect)
+		ShouldBeNil(err)
+		ShouldLen(statements, 2)
+	})
+	Convey("Unterminated strings are reported", t, func() {
+		_, err := SplitScript("SELECT 1;\nSELECT 'a;", sqliteDialect)
+		ShouldError(err)
+		ShouldEqual("unterminated quoted string at line 2", err.Error())
+	})
+}
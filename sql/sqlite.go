@@ -0,0 +1,62 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/logger"
+)
+
+// SQLiteMemory is the database name used to create an in-memory SQLite database.
+const SQLiteMemory = ":memory:"
+
+const sqliteDialect = "sqlite3"
+
+type sqliteProvider struct {
+}
+
+func (p *sqliteProvider) Dial(cfg *dbx.DbConfig) (dbx.IDatabase, error) {
+	return DialSQLite(cfg)
+}
+
+func init() {
+	if err := dbx.Register("SQLite", &sqliteProvider{}); err != nil {
+		logger.Get().Error("Unable to register SQLite provider, ", err.Error())
+	}
+}
+
+// DialSQLite opens the SQLite database file indicated by `cfg.Database`. If the database name is empty or ':memory:', a
+// new in-memory database is created. `cfg.Options` is appended to the data source name as query parameters, e.g.
+// "_foreign_keys=1". Host, port and credentials are ignored.
+//
+// An in-memory database only lives in the connection that created it, so the connection pool is limited to a single
+// connection. Operations outside of a transaction block while a transaction is in progress in that mode.
+func DialSQLite(cfg *dbx.DbConfig) (IDatabase, error) {
+	dsn := getSQLitePath(cfg)
+	db, err := gorm.Open(sqliteDialect, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open sqlite database, %s", err.Error())
+	}
+	if isSQLiteMemory(cfg) {
+		db.DB().SetMaxOpenConns(1)
+	}
+	return FromDB(db, false), nil
+}
+
+func getSQLitePath(cfg *dbx.DbConfig) string {
+	path := cfg.Database
+	if isSQLiteMemory(cfg) {
+		path = SQLiteMemory
+	}
+	if options := strings.TrimPrefix(cfg.Options, "?"); options != "" {
+		path += "?" + options
+	}
+	return path
+}
+
+func isSQLiteMemory(cfg *dbx.DbConfig) bool {
+	return cfg.Database == "" || cfg.Database == SQLiteMemory
+}
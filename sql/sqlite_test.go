@@ -0,0 +1,347 @@
+package sql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/common"
+	"github.com/jucardi/go-db/pages"
+	. "github.com/jucardi/go-testx/testx"
+)
+
+var deletedNames []string
+
+type sqliteUser struct {
+	ID     uint   `gorm:"primary_key"`
+	Name   string `gorm:"unique_index"`
+	Age    int
+	Status string
+}
+
+func (u *sqliteUser) TableName() string {
+	return "users"
+}
+
+func (u *sqliteUser) AfterDelete() error {
+	deletedNames = append(deletedNames, u.Name)
+	return nil
+}
+
+func TestSQLite(t *testing.T) {
+	db, err := dbx.Dial(&dbx.DbConfig{Database: SQLiteMemory}, "SQLite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.CreateRepo("users", &sqliteUser{}); err != nil {
+		t.Fatal(err)
+	}
+	users := db.R("users")
+	if err := users.Insert(
+		&sqliteUser{Name: "alice", Age: 30, Status: "active"},
+		&sqliteUser{Name: "bob", Age: 17, Status: "active"},
+		&sqliteUser{Name: "carol", Age: 45, Status: "inactive"},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	Convey("Filters are translated to SQL", t, func() {
+		var result []*sqliteUser
+		ShouldBeNil(users.Where(dbx.And(dbx.Eq("status", "active"), dbx.Gte("age", 18))).Or().Where(dbx.Like("name", "c%")).Sort("name").All(&result))
+		ShouldLen(result, 2)
+		ShouldEqual("alice", result[0].Name)
+		ShouldEqual("carol", result[1].Name)
+
+		n, err := users.Not(dbx.In("name", "alice", "bob")).Count()
+		ShouldBeNil(err)
+		ShouldEqual(1, n)
+
+		n, err = users.Where(dbx.Like("name", `ali\_e`)).Count()
+		ShouldBeNil(err)
+		ShouldEqual(0, n)
+
+		var first sqliteUser
+		ShouldBeNil(users.Where(1).One(&first))
+		ShouldEqual("alice", first.Name)
+
+		ShouldBeNil(db.Run("UPDATE users SET status = NULL WHERE name = 'carol'"))
+		n, err = users.Where(dbx.Ne("status", "active")).Count()
+		ShouldBeNil(err)
+		ShouldEqual(1, n)
+	})
+	Convey("A done context aborts the operations", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := users.WithContext(ctx).Where(nil).Count()
+		ShouldEqual(context.Canceled, err)
+		ShouldEqual(context.Canceled, users.WithContext(ctx).Insert(&sqliteUser{Name: "zoe"}))
+	})
+	Convey("Delete with a filter", t, func() {
+		ShouldBeNil(users.Delete(dbx.Lt("age", 18)))
+		n, err := users.Where(nil).Count()
+		ShouldBeNil(err)
+		ShouldEqual(2, n)
+	})
+	Convey("Delete honors sort and limit, and reports the affected rows", t, func() {
+		ShouldBeNil(users.Insert(&sqliteUser{Name: "dave", Age: 25}, &sqliteUser{Name: "erin", Age: 35}))
+		q := users.Where(dbx.Gt("age", 20)).Sort("-age").Limit(2)
+		ShouldBeNil(q.Delete())
+		ShouldEqual(int64(2), q.(IRowsAffected).RowsAffected())
+
+		var names []string
+		ShouldBeNil(users.Where(nil).Sort("name").Distinct("name", &names))
+		ShouldEqual([]string{"alice", "dave"}, names)
+	})
+	Convey("Delete hooks are invoked for each row when the model is known", t, func() {
+		deletedNames = nil
+		model := db.(IDatabase).Model(&sqliteUser{})
+		ShouldBeNil(model.Where(dbx.Eq("name", "dave")).Delete())
+		ShouldEqual([]string{"dave"}, deletedNames)
+	})
+	Convey("Exec maps the returned rows", t, func() {
+		var res ExecResult
+		ShouldBeNil(db.Exec("UPDATE users SET status = 'archived' WHERE name = 'alice'", &res))
+		ShouldEqual(int64(1), res.RowsAffected)
+
+		var all []*sqliteUser
+		ShouldBeNil(db.Exec("SELECT * FROM users ORDER BY name", &all))
+		ShouldLen(all, 1)
+		ShouldEqual("archived", all[0].Status)
+
+		var rows []map[string]interface{}
+		ShouldBeNil(db.Exec("SELECT name, age FROM users", &rows))
+		ShouldEqual("alice", rows[0]["name"])
+
+		var count int
+		ShouldBeNil(db.Exec("SELECT count(*) FROM users", &count))
+		ShouldEqual(1, count)
+
+		single := &sqliteUser{}
+		ShouldError(db.Exec("SELECT * FROM users WHERE name = 'nobody'", single))
+	})
+}
+
+func TestSQLiteCursor(t *testing.T) {
+	db, err := dbx.Dial(&dbx.DbConfig{Database: SQLiteMemory}, "SQLite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.CreateRepo("users", &sqliteUser{}); err != nil {
+		t.Fatal(err)
+	}
+	users := db.R("users")
+	if err := users.Insert(
+		&sqliteUser{Name: "alice", Age: 30},
+		&sqliteUser{Name: "bob", Age: 30},
+		&sqliteUser{Name: "carol", Age: 45},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	Convey("Cursor pagination uses the primary key to break ties", t, func() {
+		cursor := &pages.Cursor{Size: 2, Sort: []string{"age"}}
+		var result []*sqliteUser
+		p, err := users.Where(nil).WrapCursor(&result, cursor)
+		ShouldBeNil(err)
+		ShouldLen(result, 2)
+		ShouldEqual("alice", result[0].Name)
+		ShouldEqual("bob", result[1].Name)
+
+		result = nil
+		cursor.Token = p.NextCursor
+		p, err = users.Where(nil).WrapCursor(&result, cursor)
+		ShouldBeNil(err)
+		ShouldLen(result, 1)
+		ShouldEqual("carol", result[0].Name)
+		ShouldEqual("", p.NextCursor)
+
+		result = nil
+		cursor.Token = p.PrevCursor
+		_, err = users.Where(nil).WrapCursor(&result, cursor)
+		ShouldBeNil(err)
+		ShouldLen(result, 2)
+		ShouldEqual("alice", result[0].Name)
+	})
+}
+
+type sqliteHooked struct {
+	ID    uint `gorm:"primary_key"`
+	Name  string
+	Calls []string `gorm:"-"`
+}
+
+func (h *sqliteHooked) TableName() string {
+	return "hooked"
+}
+
+func (h *sqliteHooked) AfterCreate() error {
+	h.Calls = append(h.Calls, "AfterCreate")
+	return nil
+}
+
+func (h *sqliteHooked) AfterUpdate() error {
+	h.Calls = append(h.Calls, "AfterUpdate")
+	return nil
+}
+
+func (h *sqliteHooked) AfterFound() error {
+	h.Calls = append(h.Calls, "AfterFound")
+	return nil
+}
+
+func TestSQLiteEntityHooks(t *testing.T) {
+	db, err := dbx.Dial(&dbx.DbConfig{Database: SQLiteMemory}, "SQLite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.CreateRepo("hooked", &sqliteHooked{}); err != nil {
+		t.Fatal(err)
+	}
+	repo := db.R("hooked")
+
+	Convey("Hooks are invoked once per operation", t, func() {
+		h := &sqliteHooked{Name: "alice"}
+		ShouldBeNil(repo.Insert(h))
+		ShouldEqual([]string{"AfterCreate"}, h.Calls)
+
+		update := &sqliteHooked{Name: "bob"}
+		ShouldBeNil(repo.Where(dbx.Eq("id", h.ID)).Update(update))
+		ShouldEqual([]string{"AfterUpdate"}, update.Calls)
+
+		var all []*sqliteHooked
+		ShouldBeNil(repo.Where(nil).All(&all))
+		ShouldLen(all, 1)
+		ShouldEqual("bob", all[0].Name)
+		ShouldEqual([]string{"AfterFound"}, all[0].Calls)
+	})
+}
+
+func TestSQLiteMigrationTransactions(t *testing.T) {
+	db, err := dbx.Dial(&dbx.DbConfig{Database: SQLiteMemory}, "SQLite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	scripts := fstest.MapFS{
+		"001_accounts.sql": {Data: []byte("CREATE TABLE accounts (id INTEGER)")},
+		"002_roles.sql":    {Data: []byte("CREATE TABLE roles (id INTEGER); INSERT INTO missing VALUES (1)")},
+	}
+
+	Convey("A failed script is rolled back and recorded as failed", t, func() {
+		err := db.MigrateFS(scripts, ".")
+		ShouldError(err)
+		ShouldBeTrue(db.HasRepo("accounts"))
+		ShouldBeTrue(!db.HasRepo("roles"))
+
+		var info common.MigrationInfo
+		ShouldBeNil(db.R(common.MigrationRepo).Where(dbx.Eq("script_id", "002_roles.sql")).One(&info))
+		ShouldEqual(common.MigrationFailed, info.Status)
+		ShouldBeTrue(strings.Contains(info.Error, "missing"))
+	})
+	Convey("Scripts with the no-transaction marker are not rolled back", t, func() {
+		scripts["002_roles.sql"] = &fstest.MapFile{Data: []byte("-- dbx:no-transaction\nCREATE TABLE roles (id INTEGER); INSERT INTO missing VALUES (1)")}
+		ShouldError(db.MigrateFS(scripts, "."))
+		ShouldBeTrue(db.HasRepo("roles"))
+	})
+	Convey("A fixed script is migrated", t, func() {
+		scripts["002_roles.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE IF NOT EXISTS roles (id INTEGER)")}
+		ShouldBeNil(db.MigrateFS(scripts, "."))
+
+		var info common.MigrationInfo
+		ShouldBeNil(db.R(common.MigrationRepo).Where(dbx.Eq("script_id", "002_roles.sql")).One(&info))
+		ShouldEqual(common.MigrationApplied, info.Status)
+		ShouldEqual("", info.Error)
+	})
+}
+
+func TestSQLiteMigrationRepoUpgrade(t *testing.T) {
+	db, err := dbx.Dial(&dbx.DbConfig{Database: SQLiteMemory}, "SQLite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// The schema of the migration table created by previous versions
+	if err := db.Run("CREATE TABLE " + common.MigrationRepo + " (script_id varchar(255), hash varchar(255), timestamp datetime)"); err != nil {
+		t.Fatal(err)
+	}
+
+	Convey("The missing columns are added to an existing migration table", t, func() {
+		scripts := fstest.MapFS{"001_accounts.sql": {Data: []byte("CREATE TABLE accounts (id INTEGER)")}}
+		ShouldBeNil(db.MigrateFS(scripts, "."))
+
+		var info common.MigrationInfo
+		ShouldBeNil(db.R(common.MigrationRepo).Where(dbx.Eq("script_id", "001_accounts.sql")).One(&info))
+		ShouldEqual(common.MigrationApplied, info.Status)
+	})
+}
+
+func TestSQLiteMigrationLockTakenOver(t *testing.T) {
+	db, err := dbx.Dial(&dbx.DbConfig{Database: SQLiteMemory}, "SQLite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	lockRepo := common.MigrationRepo + "_lock"
+
+	Convey("The migration stops if the lock is taken by another instance", t, func() {
+		ran := false
+		migrator := &common.Migrator{Db: db, UseLock: true, LockLease: 30 * time.Millisecond}
+		migrator.Register("001_takeover.go", "v1", func(_ context.Context, d dbx.IDatabase) error {
+			// Simulates the lease expiring and another instance acquiring the lock
+			if err := d.R(lockRepo).Where(dbx.Eq("lock_id", "migration")).Delete(); err != nil {
+				return err
+			}
+			if err := d.R(lockRepo).Insert(common.MigrationLock{LockId: "migration", Owner: "other", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+				return err
+			}
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		})
+		migrator.Register("002_after.go", "v1", func(context.Context, dbx.IDatabase) error {
+			ran = true
+			return nil
+		})
+		for _, m := range migrator.Migrations {
+			m.NoTransaction = true
+		}
+
+		err := migrator.Migrate()
+		ShouldError(err)
+		ShouldEqual("Migration aborted before running '002_after.go', unable to renew the migration lock. the lock is held by another instance", err.Error())
+		ShouldBeFalse(ran)
+
+		var lock common.MigrationLock
+		ShouldBeNil(db.R(lockRepo).Where(dbx.Eq("lock_id", "migration")).One(&lock))
+		ShouldEqual("other", lock.Owner)
+	})
+}
+
+func TestSQLiteRunScript(t *testing.T) {
+	db, err := dbx.Dial(&dbx.DbConfig{Database: SQLiteMemory}, "SQLite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	Convey("Scripts are run by statement and errors report the line", t, func() {
+		ShouldBeNil(db.Run("CREATE TABLE notes (text TEXT);\nINSERT INTO notes VALUES ('a;b');"))
+		n, _ := db.R("notes").Where(nil).Count()
+		ShouldEqual(1, n)
+
+		err := db.Run("INSERT INTO notes VALUES ('c');\n\nINSERT INTO missing VALUES (1);")
+		ShouldError(err)
+		ShouldEqual("statement at line 3 failed, no such table: missing. Statement: INSERT INTO missing VALUES (1)", err.Error())
+	})
+}
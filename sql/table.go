@@ -1,9 +1,11 @@
 package sql
 
 import (
+	"context"
 	"database/sql"
 	"github.com/jinzhu/gorm"
 	"github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/common"
 	"github.com/jucardi/go-strings/stringx"
 	"reflect"
 )
@@ -46,28 +48,53 @@ type ITable interface {
 
 type table struct {
 	*gorm.DB
-	name string
+	name      string
+	ctx       context.Context
+	callbacks *common.CallbacksManager
 }
 
-func newTable(db *gorm.DB, model interface{}) ITable {
+func newTable(db *gorm.DB, model interface{}, callbacks *common.CallbacksManager, ctx context.Context) ITable {
 	if name, ok := model.(string); ok {
 		return &table{
-			DB:   db.Table(name),
-			name: name,
+			DB:        db.Table(name),
+			name:      name,
+			ctx:       ctx,
+			callbacks: callbacks,
 		}
 	}
 
 	return &table{
-		DB:   db.Model(model),
-		name: stringx.CamelToSnake(reflect.TypeOf(model).Elem().Name()),
+		DB:        db.Model(model),
+		name:      stringx.CamelToSnake(reflect.TypeOf(model).Elem().Name()),
+		ctx:       ctx,
+		callbacks: callbacks,
 	}
 }
 
+func (t *table) WithContext(ctx context.Context) dbx.IRepository {
+	ret := *t
+	ret.ctx = ctx
+	return &ret
+}
+
 func (t *table) Insert(docs ...interface{}) error {
-	for _, v := range docs {
-		t.DB.Create(v)
+	scope := &dbx.Scope{
+		Context:   t.ctx,
+		Operation: dbx.OpCreate,
+		Repo:      t.name,
+		Documents: docs,
 	}
-	return t.Error
+	return t.callbacks.Run(scope, func() error {
+		for _, v := range docs {
+			if t.ctx != nil && t.ctx.Err() != nil {
+				return t.ctx.Err()
+			}
+			if err := t.DB.Create(v).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (t *table) Drop() error {
@@ -75,11 +102,11 @@ func (t *table) Drop() error {
 }
 
 func (t *table) Where(condition interface{}, args ...interface{}) dbx.IQuery {
-	return newQuery(t.DB, t.name).Where(condition, args...)
+	return newQuery(t.DB, t.name, t.callbacks, t.ctx).Where(condition, args...)
 }
 
 func (t *table) Not(condition interface{}, args ...interface{}) dbx.IQuery {
-	return newQuery(t.DB, t.name).Not(condition, args...)
+	return newQuery(t.DB, t.name, t.callbacks, t.ctx).Not(condition, args...)
 }
 
 func (t *table) AddIndex(indexName string, fields ...string) error {
@@ -134,6 +161,7 @@ func (t *table) Preload(column string, conditions ...interface{}) ITable {
 	return t
 }
 
+// Delete deletes the rows that match the provided condition. See `Where` for the accepted conditions.
 func (t *table) Delete(query interface{}, args ...interface{}) error {
-	return t.DB.Delete(query, args...).Error
+	return t.Where(query, args...).Delete()
 }
@@ -1,13 +1,17 @@
 package testutils
 
 import (
+	"context"
+	"io/fs"
+
 	. "github.com/jucardi/go-db"
 	"github.com/jucardi/go-logger-lib/log"
 )
 
 // All functions that return IQuery to easily initialize
 var (
-	_ IDatabase = (*DatabaseMock)(nil)
+	_ IDatabase    = (*DatabaseMock)(nil)
+	_ ITransaction = (*DatabaseMock)(nil)
 )
 
 // QueryMock is a mock implementation of IQuery
@@ -29,6 +33,37 @@ func (db *DatabaseMock) Clone() IDatabase {
 	return db.returnDB("Clone")
 }
 
+func (db *DatabaseMock) WithContext(ctx context.Context) IDatabase {
+	return db.returnDB("WithContext", ctx)
+}
+
+func (db *DatabaseMock) Begin() (ITransaction, error) {
+	ret, err := db.ReturnSingleArgWithError("Begin")
+
+	if ret != nil {
+		return ret.(ITransaction), err
+	}
+
+	return nil, err
+}
+
+func (db *DatabaseMock) Commit() error {
+	return db.ReturnError("Commit")
+}
+
+func (db *DatabaseMock) Rollback() error {
+	return db.ReturnError("Rollback")
+}
+
+// WithTransaction registers the call and, unless the mock is set to return an error, runs the provided function using
+// the mock itself as the transaction.
+func (db *DatabaseMock) WithTransaction(fn func(tx IDatabase) error) error {
+	if err := db.ReturnError("WithTransaction", fn); err != nil {
+		return err
+	}
+	return fn(db)
+}
+
 func (db *DatabaseMock) Close() {
 	db.Invoke("Close")
 }
@@ -73,6 +108,10 @@ func (db *DatabaseMock) Migrate(dataDir string, failOnOrderMismatch ...bool) err
 	return db.ReturnError("Migrate", dataDir, failOnOrderMismatch)
 }
 
+func (db *DatabaseMock) MigrateFS(fsys fs.FS, dir string, failOnOrderMismatch ...bool) error {
+	return db.ReturnError("MigrateFS", fsys, dir, failOnOrderMismatch)
+}
+
 func (db *DatabaseMock) SetScriptExecutor(executor ScriptExecutor) {
 	db.Invoke("SetScriptExecutor")
 }
\ No newline at end of file
@@ -12,14 +12,14 @@ func TestMockAll(t *testing.T) {
 	db, repo, query := MockAll()
 
 	Convey("Database Mock", t, func() {
-		testMock(t, db, (*IDatabase)(nil), (*IDatabase)(nil), "IDatabase", "Clone")
+		testMock(t, db, (*IDatabase)(nil), (*IDatabase)(nil), "IDatabase", "Clone", "WithContext")
 		testMock(t, db, (*IDatabase)(nil), (*IRepository)(nil), "IRepository", "R", "Repo")
 	})
 	Convey("Repository Mock", t, func() {
 		testMock(t, repo, (*IRepository)(nil), (*IQuery)(nil), "IQuery", "Where", "Not")
 	})
 	Convey("Query Mock", t, func() {
-		testMock(t, query, (*IQuery)(nil), (*IQuery)(nil), "IQuery", "Limit", "Not", "Or", "Page", "Select", "Skip", "Sort", "Where")
+		testMock(t, query, (*IQuery)(nil), (*IQuery)(nil), "IQuery", "Limit", "Not", "Or", "Page", "Select", "Skip", "Sort", "Where", "WithContext")
 	})
 }
 
@@ -1,6 +1,8 @@
 package testutils
 
 import (
+	"context"
+
 	. "github.com/jucardi/go-db"
 	"github.com/jucardi/go-db/pages"
 )
@@ -25,6 +27,10 @@ func MockQuery() *QueryMock {
 	return ret
 }
 
+func (q *QueryMock) WithContext(ctx context.Context) IQuery {
+	return q.returnQuery("WithContext", ctx)
+}
+
 func (q *QueryMock) Sort(fields ...string) IQuery {
 	f := make([]interface{}, len(fields))
 	for i, v := range fields {
@@ -107,6 +113,16 @@ func (q *QueryMock) WrapPage(result interface{}, page ...*pages.Page) (*pages.Pa
 	return nil, err
 }
 
+func (q *QueryMock) WrapCursor(result interface{}, cursor *pages.Cursor) (*pages.Paginated, error) {
+	ret, err := q.ReturnSingleArgWithError("WrapCursor", result, cursor)
+
+	if ret != nil {
+		return ret.(*pages.Paginated), err
+	}
+
+	return nil, err
+}
+
 func (q *QueryMock) Count() (int, error) {
 	ret, err := q.ReturnSingleArgWithError("Count")
 
@@ -1,6 +1,8 @@
 package testutils
 
 import (
+	"context"
+
 	. "github.com/jucardi/go-db"
 )
 
@@ -20,6 +22,7 @@ func MockRepo() *RepositoryMock {
 		mockBase: newMock(),
 	}
 
+	initMock(ret, (*IRepository)(nil), ret)
 	return ret
 }
 
@@ -27,6 +30,10 @@ func (r *RepositoryMock) Insert(docs ...interface{}) error {
 	return r.ReturnError("Insert", docs...)
 }
 
+func (r *RepositoryMock) WithContext(ctx context.Context) IRepository {
+	return r.returnRepository("WithContext", ctx)
+}
+
 func (r *RepositoryMock) Drop() error {
 	return r.ReturnError("Drop")
 }
@@ -0,0 +1,71 @@
+package testutils
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/jucardi/go-db"
+	"github.com/jucardi/go-db/pages"
+	"github.com/jucardi/go-testx/mock"
+	. "github.com/jucardi/go-testx/testx"
+)
+
+type typedEntity struct {
+	ID   string `bson:"_id"`
+	Name string `bson:"name"`
+}
+
+func TestTypedRepository(t *testing.T) {
+	db, repo, query := MockAll()
+	users := NewRepository[typedEntity](db, "users")
+
+	var condition interface{}
+	repo.When("Where", func(args ...interface{}) []interface{} {
+		condition = args[0]
+		return mock.MakeReturn(query)
+	})
+	query.When("First", func(args ...interface{}) []interface{} {
+		args[0].(*typedEntity).Name = "john"
+		return mock.MakeReturn(nil)
+	})
+	query.WhenReturn("Count", 3, nil)
+
+	Convey("FindByID uses the detected identifier field", t, func() {
+		ret, err := users.FindByID("abc")
+		ShouldBeNil(err)
+		ShouldEqual("john", ret.Name)
+		ShouldEqual("_id", condition.(*Filter).Field)
+		ShouldEqual("abc", condition.(*Filter).Value)
+	})
+	Convey("Count with a nil condition matches all records", t, func() {
+		n, err := users.Count(nil)
+		ShouldBeNil(err)
+		ShouldEqual(3, n)
+		ShouldEqual(FilterAnd, condition.(*Filter).Op)
+	})
+	Convey("WrapPage returns typed items and Map preserves the counts", t, func() {
+		query.When("WrapPage", func(args ...interface{}) []interface{} {
+			items := args[0].(*[]*typedEntity)
+			*items = append(*items, &typedEntity{ID: "abc", Name: "john"})
+			ret, err := pages.CreatePaginated(args[1].(*pages.Page), items, 3)
+			return mock.MakeReturn(ret, err)
+		})
+
+		page, err := WrapPage[*typedEntity](users.Query(nil), &pages.Page{Page: 1, Size: 1})
+		ShouldBeNil(err)
+		ShouldEqual("john", page.Items[0].Name)
+
+		names := pages.Map(page, func(e *typedEntity) string { return e.Name })
+		ShouldEqual([]string{"john"}, names.Items)
+		ShouldEqual(3, names.TotalCount)
+		ShouldEqual(3, names.TotalPages)
+
+		data, err := json.Marshal(names)
+		ShouldBeNil(err)
+		ShouldEqual(`{"count":1,"total_pages":3,"total_count":3,"size":1,"current_page":1,"content":["john"]}`, string(data))
+	})
+	Convey("DeleteByID deletes through the repository", t, func() {
+		ShouldBeNil(users.DeleteByID("abc"))
+		ShouldEqual(1, repo.Times("Delete"))
+	})
+}
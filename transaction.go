@@ -1,17 +1,32 @@
 package dbx
 
-// TODO: Implement
+// ITransactional encapsulates the functions to work with transactions.
 type ITransactional interface {
-	// Begin begins a transaction
-	Begin() ITransaction
+	// Begin begins a transaction. The returned ITransaction can be used as any other IDatabase, and the repositories
+	// obtained from it (`tx.R(name)`) participate in the transaction until it is committed or rolled back.
+	Begin() (ITransaction, error)
 
-	// Commit commits a transaction
-	Commit() ITransaction
-
-	// Rollback rollback a transaction
-	Rollback() ITransaction
+	// WithTransaction begins a transaction and runs the provided function with it. The transaction is committed if the
+	// function succeeds, or rolled back if the function returns an error or panics. If the database could not revert
+	// the changes (see `ErrRollbackNotSupported`), the returned error wraps the error of the function and reports it.
+	WithTransaction(fn func(tx IDatabase) error) error
 }
 
-type ITransaction interface{
+// ITransaction represents a transaction started by ITransactional.Begin
+type ITransaction interface {
+	IDatabase
+
+	// Commit commits the transaction
+	Commit() error
+
+	// Rollback rolls back the transaction. Returns `ErrRollbackNotSupported` if the transaction was ended but the
+	// changes could not be reverted.
+	Rollback() error
+}
 
+// IAtomicTransactions is implemented by the databases which transactions are atomic, meaning the changes made in a
+// transaction that is rolled back are reverted. Some databases provide best-effort transactions only (MongoDB).
+type IAtomicTransactions interface {
+	// AtomicTransactions indicates whether the transactions of the database are atomic
+	AtomicTransactions() bool
 }
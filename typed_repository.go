@@ -0,0 +1,163 @@
+package dbx
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/jucardi/go-db/pages"
+)
+
+// Repository is a typed wrapper of IRepository for records of type T. Since it is built on top of IRepository and
+// IQuery, it can be used with any provider. For example:
+//
+//	users := dbx.NewRepository[User](db, "users")
+//	user, err := users.FindOne(dbx.Eq("email", "john@doe.com"))
+//
+// Conditions accept the same values as `IRepository.Where`; a nil condition matches all records.
+type Repository[T any] struct {
+	repo    IRepository
+	idField string
+}
+
+// NewRepository creates a typed repository of T for the repository with the given name. The identifier field is the
+// field used by `UpdateByID` and `DeleteByID`. If not provided, "_id" is used if T has a field tagged as `bson:"_id"`,
+// otherwise "id" is used.
+func NewRepository[T any](db IDatabase, name string, idField ...string) *Repository[T] {
+	return FromRepository[T](db.R(name), idField...)
+}
+
+// FromRepository creates a typed repository of T which wraps the provided repository. See `NewRepository` for more
+// information on the identifier field.
+func FromRepository[T any](repo IRepository, idField ...string) *Repository[T] {
+	ret := &Repository[T]{repo: repo}
+	if len(idField) > 0 && idField[0] != "" {
+		ret.idField = idField[0]
+	} else {
+		ret.idField = detectIdField(reflect.TypeOf((*T)(nil)).Elem())
+	}
+	return ret
+}
+
+// Repo returns the underlying repository
+func (r *Repository[T]) Repo() IRepository {
+	return r.repo
+}
+
+// WithContext returns a copy of the typed repository bound to the provided context.
+func (r *Repository[T]) WithContext(ctx context.Context) *Repository[T] {
+	return &Repository[T]{repo: r.repo.WithContext(ctx), idField: r.idField}
+}
+
+// Query returns a query for the provided condition, to be used when the typed functions are not enough.
+func (r *Repository[T]) Query(condition interface{}, args ...interface{}) IQuery {
+	if condition == nil {
+		condition = And()
+	}
+	return r.repo.Where(condition, args...)
+}
+
+// Insert inserts one or more records in the repository.
+func (r *Repository[T]) Insert(docs ...*T) error {
+	values := make([]interface{}, len(docs))
+	for i, d := range docs {
+		values[i] = d
+	}
+	return r.repo.Insert(values...)
+}
+
+// FindOne returns the first record that meets the provided condition.
+func (r *Repository[T]) FindOne(condition interface{}, args ...interface{}) (*T, error) {
+	ret := new(T)
+	if err := r.Query(condition, args...).First(ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// FindByID returns the record with the provided identifier.
+func (r *Repository[T]) FindByID(id interface{}) (*T, error) {
+	return r.FindOne(Eq(r.idField, id))
+}
+
+// FindAll returns all the records that meet the provided condition, sorted by the provided fields if any. Use '-' at
+// the beginning of a field for reverse order.
+func (r *Repository[T]) FindAll(condition interface{}, sort ...string) ([]*T, error) {
+	var ret []*T
+	q := r.Query(condition)
+	if len(sort) > 0 {
+		q = q.Sort(sort...)
+	}
+	if err := q.All(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// FindPage returns the requested page of the records that meet the provided condition. The items of the returned
+// *pages.Paginated are of type []*T.
+func (r *Repository[T]) FindPage(condition interface{}, page *pages.Page) (*pages.Paginated, error) {
+	var ret []*T
+	return r.Query(condition).WrapPage(&ret, page)
+}
+
+// FindCursor returns the subset requested by the cursor of the records that meet the provided condition, using keyset
+// pagination. The items of the returned *pages.Paginated are of type []*T.
+func (r *Repository[T]) FindCursor(condition interface{}, cursor *pages.Cursor) (*pages.Paginated, error) {
+	var ret []*T
+	return r.Query(condition).WrapCursor(&ret, cursor)
+}
+
+// UpdateByID updates the record with the provided identifier.
+func (r *Repository[T]) UpdateByID(id interface{}, update interface{}) error {
+	return r.repo.Where(Eq(r.idField, id)).Update(update)
+}
+
+// DeleteByID deletes the record with the provided identifier.
+func (r *Repository[T]) DeleteByID(id interface{}) error {
+	return r.repo.Delete(Eq(r.idField, id))
+}
+
+// Count returns the amount of records that meet the provided condition.
+func (r *Repository[T]) Count(condition interface{}, args ...interface{}) (int, error) {
+	return r.Query(condition, args...).Count()
+}
+
+// WrapPage obtains the items in the requested page of the query and wraps the result in a typed paginated result. See
+// `IQueryPageExtension.WrapPage`. For example:
+//
+//	page, err := dbx.WrapPage[*User](db.R("users").Where(dbx.Eq("status", "active")), p)
+func WrapPage[T any](q IQuery, page ...*pages.Page) (*pages.PaginatedOf[T], error) {
+	var items []T
+	ret, err := q.WrapPage(&items, page...)
+	if err != nil {
+		return nil, err
+	}
+	return pages.Typed[T](ret)
+}
+
+// WrapCursor obtains the items in the subset of the query requested by the cursor and wraps the result in a typed
+// paginated result. See `IQueryPageExtension.WrapCursor`.
+func WrapCursor[T any](q IQuery, cursor *pages.Cursor) (*pages.PaginatedOf[T], error) {
+	var items []T
+	ret, err := q.WrapCursor(&items, cursor)
+	if err != nil {
+		return nil, err
+	}
+	return pages.Typed[T](ret)
+}
+
+func detectIdField(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return "id"
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if name := strings.Split(t.Field(i).Tag.Get("bson"), ",")[0]; name == "_id" {
+			return "_id"
+		}
+	}
+	return "id"
+}